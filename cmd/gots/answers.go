@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+)
+
+// stringsFlag is a flag that can be given multiple times
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// answerFlags are the flags used to answer the configuration wizard without prompting
+type answerFlags struct {
+	answersFile   string
+	yes           bool
+	edit          bool
+	resets        stringsFlag
+	execName      string
+	hostname      string
+	image         string
+	goCompilePath string
+	workDir       string
+	platform      string
+	registry      string
+	port          int
+	funnel        bool
+	tsnet         bool
+	backend       string
+	args          stringsFlag
+	volumes       stringsFlag
+	tags          stringsFlag
+	ephemeral     bool
+	authOnce      bool
+	oauth         bool
+}
+
+func newAnswerFlags() *answerFlags {
+	a := &answerFlags{}
+	flag.StringVar(&a.answersFile, "answers", "", "A JSON, YAML (.yaml), or TOML (.toml) file in the .gots format whose values are used instead of prompting during -config.")
+	flag.BoolVar(&a.yes, "yes", false, "Don't ask for confirmation during -config.")
+	flag.BoolVar(&a.edit, "edit", false, "Request every configuration value again using the current values as the defaults (-config).")
+	flag.Var(&a.resets, "reset", "Request the named configuration field (e.g. Port) again using its current value as the default. May be repeated (-config).")
+	flag.StringVar(&a.execName, "exec-name", "", "The name of the executable (-config).")
+	flag.StringVar(&a.hostname, "hostname", "", "The hostname to use in the docker container (-config, go applications use -exec-name).")
+	flag.StringVar(&a.image, "image", "", "The name of the docker image to execute (-config, go applications use -exec-name).")
+	flag.StringVar(&a.goCompilePath, "go-compile-path", "", "The path to the directory that contains the main.go (-config).")
+	flag.StringVar(&a.workDir, "workdir", "", "The application's working directory (-config).")
+	flag.StringVar(&a.platform, "platform", "", "The platform of the Docker host e.g. linux/arm64 (-config).")
+	flag.StringVar(&a.registry, "registry", "", "The registry to push the image to e.g. localhost:5000 (-config).")
+	flag.IntVar(&a.port, "port", 0, "The TCP port used by the application (-config).")
+	flag.BoolVar(&a.funnel, "funnel", false, "Start a Tailscale funnel (-config).")
+	flag.StringVar(&a.backend, "backend", "", "Run the executable with docker or as a systemd user service, go only (-config).")
+	flag.BoolVar(&a.tsnet, "tsnet", false, "Join the tailnet with tsnet instead of a tailscale container, go only (-config).")
+	flag.Var(&a.args, "arg", "A command line argument to pass to the executable. May be repeated (-config).")
+	flag.Var(&a.volumes, "volume", "A host:docker volume to mount. May be repeated (-config).")
+	flag.Var(&a.tags, "tag", "An ACL tag (e.g. tag:server) for the Tailscale nodes. May be repeated (-config).")
+	flag.BoolVar(&a.ephemeral, "ephemeral", false, "Make the Tailscale nodes ephemeral (-config).")
+	flag.BoolVar(&a.authOnce, "auth-once", false, "Only use TS_AUTHKEY when a Tailscale node isn't logged in (-config).")
+	flag.BoolVar(&a.oauth, "oauth", false, "TS_AUTHKEY is an OAuth client secret, requires -tag (-config).")
+	return a
+}
+
+// editing returns true if existing configuration values are to be edited
+func (a *answerFlags) editing() bool {
+	return a.edit || len(a.resets) > 0
+}
+
+// apply adds the answers from the answers file and then the flags that were set on the command line to b
+func (a *answerFlags) apply(b *builder.Builder) error {
+	if a.yes {
+		b.AssumeYes()
+	}
+	if a.edit {
+		b.Edit()
+	}
+	resources := reflect.TypeOf(config.Resources{})
+	for _, name := range a.resets {
+		// Resources is requested field by field
+		if name == "Resources" {
+			for i := 0; i < resources.NumField(); i++ {
+				b.Reset(resources.Field(i).Name)
+			}
+			continue
+		}
+		// A service's fields are named like Services.db.Env
+		field, _, _ := strings.Cut(name, ".")
+		_, isField := reflect.TypeOf(config.Config{}).FieldByName(field)
+		_, isResource := resources.FieldByName(name)
+		if !isField && !isResource {
+			return fmt.Errorf("Unknown configuration field %s\n", name)
+		}
+		b.Reset(name)
+	}
+
+	if a.answersFile != "" {
+		answers, err := config.LoadAnswers(a.answersFile)
+		if err != nil {
+			return err
+		}
+		for name, value := range answers.Answers() {
+			b.Answer(name, value)
+		}
+	}
+
+	var err error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "exec-name":
+			b.Answer("ExecName", a.execName)
+		case "hostname":
+			b.Answer("DockerHostname", a.hostname)
+		case "image":
+			b.Answer("DockerImage", a.image)
+		case "go-compile-path":
+			b.Answer("GoCompilePath", a.goCompilePath)
+		case "workdir":
+			b.Answer("WorkDir", a.workDir)
+		case "platform":
+			b.Answer("TargetPlatform", a.platform)
+		case "registry":
+			b.Answer("Registry", a.registry)
+		case "port":
+			b.Answer("Port", a.port)
+		case "funnel":
+			b.Answer("Funnel", a.funnel)
+		case "tsnet":
+			b.Answer("GoTsnet", a.tsnet)
+		case "backend":
+			b.Answer("Backend", a.backend)
+		case "arg":
+			b.Answer("ExecArgs", []string(a.args))
+		case "tag":
+			b.Answer("TailscaleTags", []string(a.tags))
+		case "ephemeral":
+			b.Answer("TailscaleEphemeral", a.ephemeral)
+		case "auth-once":
+			b.Answer("TailscaleAuthOnce", a.authOnce)
+		case "oauth":
+			b.Answer("TailscaleOAuth", a.oauth)
+		case "volume":
+			volumes := []config.Volume{}
+			for _, v := range a.volumes {
+				hostDir, dockerDir, ok := strings.Cut(v, ":")
+				if !ok {
+					err = fmt.Errorf("Volume %s must be in the form host:docker\n", v)
+					return
+				}
+				volumes = append(volumes, config.Volume{DockerDir: dockerDir, HostDir: hostDir})
+			}
+			b.Answer("DockerVolumes", config.VolumesToStrings(volumes))
+		}
+	})
+	return err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func Ptr[T any](t T) *T {
+	return &t
+}
+
+// configured returns a complete dockerimage configuration with resource limits
+func configured(t *testing.T) *config.Config {
+	answers := config.Config{
+		DockerImage:    Ptr("nginx"),
+		DockerHostname: Ptr("web"),
+		WorkDir:        Ptr(t.TempDir()),
+		Funnel:         Ptr(true),
+		TargetPlatform: Ptr("linux/amd64"),
+		Port:           Ptr(80),
+		Resources:      &config.Resources{Memory: Ptr("1g"), CPUs: Ptr("2"), NoFile: Ptr(1024), StopTimeout: Ptr("10s")},
+	}
+	b := builder.New(strings.NewReader(""), builder.AppTypeDockerImage).AssumeYes()
+	for name, value := range answers.Answers() {
+		b.Answer(name, value)
+	}
+	cfg := &config.Config{Type: builder.AppTypeDockerImage}
+	require.NoError(t, cfg.RequestMissingConfiguration(b))
+	require.Empty(t, cfg.Validate())
+	// Only the reset fields are requested again
+	cfg.TailscaleEphemeral = Ptr(false)
+	cfg.TailscaleAuthOnce = Ptr(false)
+	return cfg
+}
+
+func TestApplyReset(t *testing.T) {
+	t.Run("resource", func(t *testing.T) {
+		cfg := configured(t)
+		b := builder.New(strings.NewReader("512m"), builder.AppTypeDockerImage).AssumeYes()
+		require.NoError(t, (&answerFlags{resets: stringsFlag{"Memory"}}).apply(b))
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		require.Equal(t, &config.Resources{Memory: Ptr("512m"), CPUs: Ptr("2"), NoFile: Ptr(1024), StopTimeout: Ptr("10s")}, cfg.Resources)
+	})
+
+	t.Run("all resources", func(t *testing.T) {
+		cfg := configured(t)
+		b := builder.New(strings.NewReader("512m 1.5 4096 30s"), builder.AppTypeDockerImage).AssumeYes()
+		require.NoError(t, (&answerFlags{resets: stringsFlag{"Resources"}}).apply(b))
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		require.Equal(t, &config.Resources{Memory: Ptr("512m"), CPUs: Ptr("1.5"), NoFile: Ptr(4096), StopTimeout: Ptr("30s")}, cfg.Resources)
+	})
+
+	t.Run("service field", func(t *testing.T) {
+		b := builder.New(strings.NewReader(""), builder.AppTypeDockerImage)
+		require.NoError(t, (&answerFlags{resets: stringsFlag{"Services.db.Env"}}).apply(b))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		b := builder.New(strings.NewReader(""), builder.AppTypeDockerImage)
+		require.EqualError(t, (&answerFlags{resets: stringsFlag{"Nope"}}).apply(b), "Unknown configuration field Nope\n")
+	})
+}
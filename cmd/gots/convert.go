@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/efarrer/gots/config"
+)
+
+// configCommand runs the configuration file subcommands (just convert for now)
+func configCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "convert" {
+		return fmt.Errorf("Usage: gots config convert -to json|yaml|toml\n")
+	}
+	return convertCommand(ctx, args[1:])
+}
+
+// convertCommand rewrites the configuration file in another format. The original is renamed to <file>.bak.
+func convertCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	to := flags.String("to", "", "The format to convert the configuration to (json, yaml, or toml).")
+	flags.Parse(args)
+
+	format, err := config.ParseFormat(*to)
+	if err != nil {
+		return err
+	}
+	if !config.Exists() {
+		return fmt.Errorf("There is no configuration to convert, run gots with -config\n")
+	}
+	from, err := config.Path()
+	if err != nil {
+		return err
+	}
+	if config.FormatOf(from) == format {
+		fmt.Printf("%s is already %s\n", from, format)
+		return nil
+	}
+	toPath := config.PathFor(format)
+	if _, err := os.Stat(toPath); err == nil {
+		return fmt.Errorf("%s already exists\n", toPath)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	err = cfg.SaveTo(toPath)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(from, from+".bak")
+	if err != nil {
+		return fmt.Errorf("Unable to rename %s %w\n", from, err)
+	}
+	fmt.Printf("Converted %s to %s (the original was renamed to %s.bak)\n", from, toPath, from)
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/efarrer/gots/deploy"
+)
+
+// logsFlags parses the logs flags. The application's logs are shown unless only -sidecar is given.
+func logsFlags(args []string) (deploy.LogOptions, string, error) {
+	opts := deploy.LogOptions{}
+	flags := flag.NewFlagSet("logs", flag.ExitOnError)
+	flags.BoolVar(&opts.Follow, "f", false, "Follow the log output.")
+	flags.StringVar(&opts.Since, "since", "", "Show logs since a timestamp (e.g. 2013-01-02T13:23:37Z) or relative time (e.g. 42m).")
+	flags.BoolVar(&opts.App, "app", false, "Show the logs of the application and its services (the default unless -sidecar is given).")
+	flags.BoolVar(&opts.Sidecar, "sidecar", false, "Show the Tailscale sidecar's logs (use -app too for both).")
+	service := flags.String("service", "", "Only show the logs of the named service (defaults to all of them).")
+	flags.Parse(args)
+
+	appSet := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "app" {
+			appSet = true
+		}
+	})
+	if !appSet && !opts.Sidecar {
+		opts.App = true
+	}
+	if !opts.App && !opts.Sidecar {
+		return opts, "", fmt.Errorf("Nothing to show use -app or -sidecar\n")
+	}
+	return opts, *service, nil
+}
+
+// logsCommand streams the application and/or Tailscale sidecar logs
+func logsCommand(ctx context.Context, args []string) error {
+	opts, service, err := logsFlags(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadCompleteConfig()
+	if err != nil {
+		return err
+	}
+
+	targets, err := deploy.Targets(cfg, service)
+	if err != nil {
+		return err
+	}
+
+	return deploy.Logs(ctx, cfg, targets, opts)
+}
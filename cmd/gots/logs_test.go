@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/deploy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogsFlags(t *testing.T) {
+	for _, tt := range []struct {
+		args []string
+		opts deploy.LogOptions
+	}{
+		{args: []string{}, opts: deploy.LogOptions{App: true}},
+		{args: []string{"-sidecar"}, opts: deploy.LogOptions{Sidecar: true}},
+		{args: []string{"-app", "-sidecar"}, opts: deploy.LogOptions{App: true, Sidecar: true}},
+		{args: []string{"-f", "-since", "10m"}, opts: deploy.LogOptions{App: true, Follow: true, Since: "10m"}},
+	} {
+		opts, _, err := logsFlags(tt.args)
+		require.NoError(t, err)
+		require.Equal(t, tt.opts, opts, tt.args)
+	}
+
+	_, service, err := logsFlags([]string{"-service", "db"})
+	require.NoError(t, err)
+	require.Equal(t, "db", service)
+
+	_, _, err = logsFlags([]string{"-app=false"})
+	require.EqualError(t, err, "Nothing to show use -app or -sidecar\n")
+}
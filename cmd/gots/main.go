@@ -1,25 +1,94 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path"
 	"strings"
+	"syscall"
+	"time"
 
 	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/deploy"
 	"github.com/efarrer/gots/env"
 	"github.com/efarrer/gots/run"
+	"github.com/efarrer/gots/state"
 )
 
-const TS_AUTHKEY_ERR = 9 // Magic number that is used to know if we need to set TS_AUTHKEY (see config/gots-run.template)
-
 var targetTypes = mapset.NewSet[string]("go", "dockerimage", "dockerfile")
 
+// subcommands are run as `gots <subcommand> [flags]`
+var subcommands = map[string]func(ctx context.Context, args []string) error{
+	"status":   statusCommand,
+	"logs":     logsCommand,
+	"migrate":  migrateCommand,
+	"validate": validateCommand,
+	"config":   configCommand,
+	"watch":    watchCommand,
+	"rollback": rollbackCommand,
+	"push":     pushCommand,
+}
+
+// loadConfig loads the .gots and migrates it to the current version
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.NeedsMigration() && config.Exists() {
+		fmt.Fprintf(os.Stderr, "The configuration is version %d, run gots migrate to upgrade it to version %d\n", cfg.Version, config.CurrentVersion)
+	}
+	err = cfg.Migrate()
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadCompleteConfig loads the .gots, makes sure that it is complete, and that its container engine is installed
+func loadCompleteConfig() (*config.Config, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.ValidateComplete() {
+		return nil, fmt.Errorf("Configuration is not complete re-run gots with -config (gots validate lists the problems)\n")
+	}
+	validateEnv(cfg)
+	return cfg, nil
+}
+
+// validateEnv makes sure that the programs used to run the application are installed
+func validateEnv(cfg *config.Config) {
+	if cfg.Systemd() {
+		env.ValidateSystemdEnv(cfg.Tsnet())
+		return
+	}
+	env.ValidateEnv(cfg.ContainerEngine().Name())
+}
+
 func main() {
+	// Ctrl-C stops the command that's running (e.g. a build) so gots can report it and exit
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			err := subcommand(ctx, os.Args[2:])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", strings.TrimSuffix(err.Error(), "\n"))
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	configType := ""
 	generateFlag := false
 	startFlag := false
@@ -32,22 +101,35 @@ func main() {
 	flag.BoolVar(&stopFlag, "stop", false, "Stop the Docker containers.")
 	flag.BoolVar(&restartFlag, "restart", false, "Stop then start the Docker containers..")
 	flag.BoolVar(&updateFlag, "update", false, "Pull the latest Docker containers then stop and start the Docker containers..")
+	service := ""
+	flag.StringVar(&service, "service", "", "Only start, stop, or restart the named service (defaults to all of them).")
+	pushFlag := false
+	flag.BoolVar(&pushFlag, "push", false, "Push the image to the configured Registry when starting (remote Docker hosts then pull it).")
+	flag.DurationVar(&deploy.BuildTimeout, "build-timeout", deploy.BuildTimeout, "How long building, pushing, or pulling the application's image can take before it's stopped.")
+	answers := newAnswerFlags()
 	flag.Parse()
 
-	env.ValidateEnv()
-
 	if restartFlag || updateFlag {
 		startFlag = true
 		stopFlag = true
 	}
-	cfg := config.Load()
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		os.Exit(1)
+	}
+
+	validateEnv(cfg)
+
+	// Editing uses the existing configuration's type
+	if configType == "" && answers.editing() {
+		configType = cfg.Type
+	}
 
 	if configType == "" && !startFlag && !generateFlag && !stopFlag {
 		flag.Usage()
 	}
 
-	cfg.Migrate()
-
 	// Config
 	if configType != "" {
 		if !targetTypes.Contains(strings.ToLower(configType)) {
@@ -55,7 +137,13 @@ func main() {
 			return
 		}
 		cfg.Type = strings.ToLower(configType)
-		err := cfg.RequestMissingConfiguration()
+		b := builder.New(os.Stdin, cfg.Type)
+		err := answers.apply(b)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s", err.Error())
+			os.Exit(1)
+		}
+		err = cfg.RequestMissingConfiguration(b)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s", err.Error())
 			os.Exit(1)
@@ -71,15 +159,17 @@ func main() {
 	// Validate for generate or start or update
 	if generateFlag || startFlag || updateFlag {
 		if !cfg.ValidateComplete() {
-			fmt.Fprintf(os.Stderr, "Configuration is not complete re-run gots with -config\n")
+			fmt.Fprintf(os.Stderr, "Configuration is not complete re-run gots with -config (gots validate lists the problems)\n")
 			return
 		}
 	}
 
-	// Pull for update
-	if updateFlag {
-		for _, image := range []string{"ubuntu:latest", "tailscale/tailscale:latest"} {
-			_, _, err := run.RunWithOutput("docker", "pull", image)
+	// Pull for update. systemd services don't use any images.
+	if updateFlag && !cfg.Systemd() {
+		// The base image is used for local builds and the tailscale image is run on the (possibly remote) engine
+		e := cfg.ContainerEngine()
+		for image, env := range map[string][]string{"ubuntu:latest": e.Local().Env(), "tailscale/tailscale:latest": e.Env()} {
+			_, err := run.Run(ctx, run.Cmd{Name: e.Name(), Args: []string{"pull", image}, Env: env, Stream: true, Prefix: "pull " + image + " | "})
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "unable to pull %s\n", image)
 				return
@@ -97,73 +187,73 @@ func main() {
 		return
 	}
 
-	// Make a temp dir
-	tempDir, err := os.MkdirTemp("", "gots")
+	stateDir, err := prepareStateDir(cfg)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to create temp dir %s\n", err)
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+		os.Exit(1)
 	}
-	defer os.RemoveAll(tempDir)
-
-	// Create a subdirectory so the docker containers have consistent names
-	tempDir = path.Join(tempDir, *cfg.DockerHostname)
-	err = os.Mkdir(tempDir, 0700)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to create %s %s\n", tempDir, err)
-		return
-	}
-
-	// Copy .gots to temp dir
-	data, err := os.ReadFile(".gots")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to read .gots %s\n", err)
-		return
-	}
-	err = os.WriteFile(path.Join(tempDir, ".gots"), data, 0644)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to write .gots %s\n", err)
-		return
-	}
-
-	// Change to temp dir
-	err = os.Chdir(tempDir)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to change to %s dir %s\n", tempDir, err)
-		return
-	}
-
-	// Generate files in temp dir
-	cfg.Generate("./")
 
 	// Start
 	if startFlag {
-		stdout, stderr, err := run.RunWithOutput("./gots-run")
+		err := deploy.Start(ctx, cfg, stateDir, service, pushFlag)
 		if err != nil {
-			var exitError *exec.ExitError
-			if errors.As(err, &exitError) {
-				if exitError.ExitCode() == TS_AUTHKEY_ERR {
-					fmt.Fprintf(os.Stderr, "TS_AUTHKEY environment variable must be set\n")
-					return
-				}
+			if errors.Is(err, deploy.ErrMissingAuthKey) {
+				fmt.Fprintf(os.Stderr, "TS_AUTHKEY environment variable must be set\n")
+				os.Exit(1)
 			}
-			fmt.Fprintf(os.Stderr, "Unable to execute gots-run %s\n", err)
-			fmt.Fprintf(os.Stderr, "Stdout:\n")
-			fmt.Fprintf(os.Stderr, "%s\n", stdout)
-			fmt.Fprintf(os.Stderr, "Stderr:\n")
-			fmt.Fprintf(os.Stderr, "%s\n", stderr)
+			fmt.Fprintf(os.Stderr, "Unable to start %s: %s\n", *cfg.DockerHostname, err)
+			os.Exit(1)
 		}
+		recordDeployment(stateDir, "start", service)
 		return
 	}
 
 	// Stop
 	if stopFlag {
-		stdout, stderr, err := run.RunWithOutput("./gots-run", "-stop")
+		err := deploy.Stop(ctx, cfg, stateDir, service)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Unable to execute gots-run -stop %s\n", err)
-			fmt.Fprintf(os.Stderr, "Stdout:\n")
-			fmt.Fprintf(os.Stderr, "%s\n", stdout)
-			fmt.Fprintf(os.Stderr, "Stderr:\n")
-			fmt.Fprintf(os.Stderr, "%s\n", stderr)
+			fmt.Fprintf(os.Stderr, "Unable to stop %s: %s\n", *cfg.DockerHostname, err)
+			os.Exit(1)
 		}
+		recordDeployment(stateDir, "stop", service)
 		return
 	}
 }
+
+// prepareStateDir copies the configuration to the application's state dir and generates the files used to run it
+// there. The generated files are kept in a stable per-application directory so the bind mounts outlive gots.
+func prepareStateDir(cfg *config.Config) (string, error) {
+	stateDir, err := state.Create(*cfg.DockerHostname)
+	if err != nil {
+		return "", err
+	}
+
+	// Copy the configuration to the state dir
+	configPath, err := config.Path()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s %s\n", configPath, err)
+	}
+	err = os.WriteFile(path.Join(stateDir, path.Base(configPath)), data, 0644)
+	if err != nil {
+		return "", fmt.Errorf("Unable to write %s %s\n", configPath, err)
+	}
+
+	// Generate files in the state dir
+	err = cfg.Generate(stateDir)
+	if err != nil {
+		return "", err
+	}
+	return stateDir, nil
+}
+
+// recordDeployment saves the deployment to the state dir. Failing to do so isn't fatal.
+func recordDeployment(stateDir string, action string, service string) {
+	err := state.SaveDeployment(stateDir, state.Deployment{Action: action, Service: service, Time: time.Now()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s", err.Error())
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/efarrer/gots/config"
+)
+
+// migrateCommand upgrades the .gots to the current version after backing it up
+func migrateCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	flags.Parse(args)
+
+	if !config.Exists() {
+		return fmt.Errorf("There is no configuration to migrate, run gots with -config\n")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if !cfg.NeedsMigration() {
+		fmt.Printf("The configuration is already version %d\n", cfg.Version)
+		return nil
+	}
+
+	from := cfg.Version
+	err = cfg.Migrate()
+	if err != nil {
+		return err
+	}
+	backupPath, err := config.Backup()
+	if err != nil {
+		return err
+	}
+	err = cfg.Save()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Migrated the configuration from version %d to %d (the original was saved to %s)\n", from, cfg.Version, backupPath)
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/state"
+)
+
+// pushCommand pushes the application's most recently built image to its registry
+func pushCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("push", flag.ExitOnError)
+	flags.Parse(args)
+
+	cfg, err := loadCompleteConfig()
+	if err != nil {
+		return err
+	}
+
+	// The current image's tag is pushed too so other hosts can roll back to it
+	stateDir, err := state.Dir(config.Deref(cfg.DockerHostname))
+	if err != nil {
+		return err
+	}
+	history, err := state.LoadHistory(stateDir)
+	if err != nil {
+		return err
+	}
+	err = deploy.Push(ctx, cfg, history.Current)
+	if err != nil {
+		return fmt.Errorf("Unable to push %s: %w", config.Deref(cfg.DockerImage), err)
+	}
+	fmt.Printf("Pushed %s\n", cfg.RegistryImage())
+	return nil
+}
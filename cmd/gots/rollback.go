@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/state"
+)
+
+// rollbackCommand runs a previously deployed image of the application or lists the deployed images
+func rollbackCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("rollback", flag.ExitOnError)
+	list := flags.Bool("list", false, "List the deployed images instead of rolling back.")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: gots rollback [-list] [tag]\nRolls back to the image deployed before the current one or the one with the tag.\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("Only one tag can be rolled back to\n")
+	}
+
+	cfg, err := loadCompleteConfig()
+	if err != nil {
+		return err
+	}
+
+	if *list {
+		stateDir, err := state.Dir(config.Deref(cfg.DockerHostname))
+		if err != nil {
+			return err
+		}
+		history, err := state.LoadHistory(stateDir)
+		if err != nil {
+			return err
+		}
+		if len(history.Images) == 0 {
+			fmt.Printf("No images have been deployed\n")
+		}
+		images := slices.Clone(history.Images)
+		slices.Reverse(images)
+		for _, image := range images {
+			current := " "
+			if image.Tag == history.Current {
+				current = "*"
+			}
+			fmt.Printf("%s %s (deployed %s)\n", current, image.Tag, image.Time.Local().Format("2006-01-02 15:04:05"))
+		}
+		return nil
+	}
+
+	stateDir, err := prepareStateDir(cfg)
+	if err != nil {
+		return err
+	}
+	tag, err := deploy.Rollback(ctx, cfg, stateDir, flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("Unable to roll back %s: %w", config.Deref(cfg.DockerHostname), err)
+	}
+	recordDeployment(stateDir, "rollback", "")
+	fmt.Printf("Rolled back %s to %s\n", config.Deref(cfg.DockerHostname), cfg.ImageTagged(tag))
+	return nil
+}
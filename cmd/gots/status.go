@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/state"
+)
+
+// statusCommand reports whether the application's containers and tailnet node are up
+func statusCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("status", flag.ExitOnError)
+	jsonFlag := flags.Bool("json", false, "Output the status as JSON.")
+	service := flags.String("service", "", "Only show the status of the named service (defaults to all of them).")
+	flags.Parse(args)
+
+	cfg, err := loadCompleteConfig()
+	if err != nil {
+		return err
+	}
+
+	targets, err := deploy.Targets(cfg, *service)
+	if err != nil {
+		return err
+	}
+
+	status, err := deploy.GetStatus(ctx, cfg, targets)
+	if err != nil {
+		return err
+	}
+
+	stateDir, err := state.Dir(*cfg.DockerHostname)
+	if err != nil {
+		return err
+	}
+	last, err := state.LoadDeployment(stateDir)
+	if err != nil {
+		return err
+	}
+
+	if *jsonFlag {
+		data, err := json.MarshalIndent(struct {
+			*deploy.Status
+			LastDeployment *state.Deployment `json:",omitempty"`
+		}{status, last}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Unable to JSONify status\n")
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if cfg.Systemd() {
+		fmt.Printf("Units:\n")
+	} else {
+		fmt.Printf("Containers:\n")
+	}
+	if len(status.Containers) == 0 {
+		fmt.Printf("  none\n")
+	}
+	for _, c := range status.Containers {
+		state := c.State
+		if c.Health != "" {
+			state += ", " + c.Health
+		}
+		fmt.Printf("  %s: %s (restarts: %d)\n", c.Service, state, c.RestartCount)
+	}
+	fmt.Printf("Tailnet:\n")
+	for _, node := range status.Nodes {
+		switch {
+		case !node.InTailnet:
+			fmt.Printf("  %s: not found\n", node.Hostname)
+		case node.Online:
+			fmt.Printf("  %s: online %s %s (funnel: %t)\n", node.Hostname, node.DNSName, strings.Join(node.TailscaleIPs, " "), node.Funnel)
+		default:
+			fmt.Printf("  %s: offline %s %s (funnel: %t)\n", node.Hostname, node.DNSName, strings.Join(node.TailscaleIPs, " "), node.Funnel)
+		}
+	}
+	if last != nil {
+		target := last.Service
+		if target == "" {
+			target = "all services"
+		}
+		fmt.Printf("Last deployment:\n  %s %s at %s\n", last.Action, target, last.Time.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+
+	"github.com/efarrer/gots/config"
+)
+
+// validateCommand checks the .gots and reports each problem with the field that caused it
+func validateCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	jsonFlag := flags.Bool("json", false, "Output the problems as JSON.")
+	flags.Parse(args)
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	problems := cfg.Validate()
+	if *jsonFlag {
+		if problems == nil {
+			problems = []config.FieldError{}
+		}
+		data, err := json.MarshalIndent(problems, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Unable to JSONify problems\n")
+		}
+		fmt.Println(string(data))
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("The configuration has %d problem(s)\n", len(problems))
+	}
+	if !*jsonFlag {
+		fmt.Println("The configuration is valid")
+	}
+	return nil
+}
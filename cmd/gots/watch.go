@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/watch"
+)
+
+// watchCommand starts the application then rebuilds and redeploys it whenever its source changes
+func watchCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	interval := flags.Duration("interval", 500*time.Millisecond, "How often the source directory is checked for changes.")
+	debounce := flags.Duration("debounce", time.Second, "How long the source must be unchanged before redeploying.")
+	flags.DurationVar(&deploy.BuildTimeout, "build-timeout", deploy.BuildTimeout, "How long building the application can take before it's stopped.")
+	flags.Parse(args)
+
+	cfg, err := loadCompleteConfig()
+	if err != nil {
+		return err
+	}
+	if cfg.Type == builder.AppTypeDockerImage {
+		return fmt.Errorf("There's nothing to rebuild for %s applications\n", builder.AppTypeDockerImage)
+	}
+
+	stateDir, err := prepareStateDir(cfg)
+	if err != nil {
+		return err
+	}
+	app := config.Deref(cfg.DockerHostname)
+	err = deploy.Start(ctx, cfg, stateDir, "", false)
+	if err != nil {
+		if errors.Is(err, deploy.ErrMissingAuthKey) {
+			return fmt.Errorf("TS_AUTHKEY environment variable must be set\n")
+		}
+		return fmt.Errorf("Unable to start %s: %w", app, err)
+	}
+	recordDeployment(stateDir, "start", "")
+
+	configPath, err := config.Path()
+	if err != nil {
+		return err
+	}
+	workDir := config.Deref(cfg.WorkDir)
+	w := watch.Watcher{
+		Dir: workDir,
+		// Editing the configuration doesn't change the application
+		Ignore:   []string{"/" + filepath.Base(configPath)},
+		Interval: *interval,
+		Debounce: *debounce,
+	}
+	fmt.Printf("Watching %s for changes (hit Ctrl-C to stop watching, %s keeps running)\n", workDir, app)
+	return w.Run(ctx.Done(), func(files []string) {
+		fmt.Printf("Redeploying %s for changes to %s\n", app, summarize(files))
+		// A failed build is reported and the previous version is left running until the next change
+		err := deploy.Execute(ctx, deploy.RedeploySteps(cfg, stateDir))
+		// Ctrl-C stops a redeploy that's in progress
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to redeploy %s: %s\n", app, err)
+			return
+		}
+		recordDeployment(stateDir, "redeploy", "")
+		fmt.Printf("Redeployed %s\n", app)
+	})
+}
+
+// summarize lists the first few changed files
+func summarize(files []string) string {
+	const most = 3
+	if len(files) <= most {
+		return strings.Join(files, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(files[:most], ", "), len(files)-most)
+}
@@ -16,6 +16,9 @@ const (
 	AppTypeGo          = "go"
 	AppTypeDockerImage = "dockerimage"
 	AppTypeDockerFile  = "dockerfile"
+
+	// TagOptional marks a field that doesn't need to be set for the configuration to be complete
+	TagOptional = "optional"
 )
 
 // GetFieldValueByName retrieves the field value from a struct by name
@@ -33,7 +36,6 @@ func GetFieldValueByName[T any](s interface{}, fieldName string) T {
 	// Ensure we are dealing with a struct.
 	if val.Kind() != reflect.Struct {
 		panic(fmt.Sprintf("Warning: Input is not a struct or a pointer to a struct. Got %v\n", val.Kind()))
-		return zeroValue
 	}
 
 	// Get the field by its name.
@@ -42,7 +44,6 @@ func GetFieldValueByName[T any](s interface{}, fieldName string) T {
 	// Check if the field was found and is valid.
 	if !field.IsValid() {
 		panic(fmt.Sprintf("Warning: Field '%s' not found or is unexported in the struct.\n", fieldName))
-		return zeroValue
 	}
 
 	// Check if the field's type is assignable to the generic type T.
@@ -50,7 +51,6 @@ func GetFieldValueByName[T any](s interface{}, fieldName string) T {
 	if !field.CanConvert(reflect.TypeOf(zeroValue)) {
 		panic(fmt.Sprintf("Warning: Field '%s' type (%v) is not assignable to expected type %T.\n",
 			fieldName, field.Type(), zeroValue))
-		return zeroValue
 	}
 
 	// Convert the field's value to the generic type T and return it.
@@ -72,7 +72,6 @@ func GetFieldTags(s interface{}, fieldName string) mapset.Set[string] {
 	// Ensure we are dealing with a struct
 	if val.Kind() != reflect.Struct {
 		panic(fmt.Sprintf("Warning: Input is not a struct or a pointer to a struct. Got %v\n", val.Kind()))
-		return tags
 	}
 
 	// Get the reflect.Type of the struct
@@ -91,7 +90,6 @@ func GetFieldTags(s interface{}, fieldName string) mapset.Set[string] {
 	}
 
 	panic(fmt.Sprintf("Warning: Field '%s' not found in the struct.\n", fieldName))
-	return tags
 }
 
 // A Builder sets the needed files in a config.Config
@@ -99,7 +97,11 @@ type Builder struct {
 	at          string
 	needsConfig bool
 	dryRun      bool
+	assumeYes   bool
+	edit        bool
+	resets      mapset.Set[string]
 	input       io.Reader
+	answers     map[string]any
 }
 
 // New creates a new Builder for the given app type
@@ -108,7 +110,11 @@ func New(reader io.Reader, at string) *Builder {
 		at:          at,
 		needsConfig: false,
 		dryRun:      false,
+		assumeYes:   false,
+		edit:        false,
+		resets:      mapset.NewSet[string](),
 		input:       reader,
+		answers:     map[string]any{},
 	}
 }
 
@@ -117,18 +123,90 @@ func (b *Builder) DryRun() *Builder {
 	return b
 }
 
+// AssumeYes answers yes to any confirmation instead of reading from the input
+func (b *Builder) AssumeYes() *Builder {
+	b.assumeYes = true
+	return b
+}
+
+// Edit requests fields even if they already have a value. The current value is the default for single values and
+// entries can be removed from or added to slices. Computed fields are only computed again if they're reset.
+func (b *Builder) Edit() *Builder {
+	b.edit = true
+	return b
+}
+
+// Reset is like Edit but only for the named fields. A computed field is computed again and the result is the default
+// if it's also requested.
+func (b *Builder) Reset(fldNames ...string) *Builder {
+	b.resets.Append(fldNames...)
+	return b
+}
+
+// editing returns true if fldName should be requested even if it has a value
+func (b *Builder) editing(fldName string) bool {
+	return b.edit || b.resetting(fldName)
+}
+
+// resetting returns true if fldName was reset. Resetting a field also resets the fields nested in it (e.g.
+// Services.db.Env is reset with Services).
+func (b *Builder) resetting(fldName string) bool {
+	parent, _, _ := strings.Cut(fldName, ".")
+	return b.resets.Contains(fldName) || b.resets.Contains(parent)
+}
+
+// Answer provides the value for a field so it isn't requested from the input. The value must either be a V or a []V
+// matching the type of the Compute/Request call for the field. Answers replace existing values but are still
+// ignored for fields that don't apply to the app type.
+func (b *Builder) Answer(fldName string, value any) *Builder {
+	b.answers[fldName] = value
+	return b
+}
+
 func (b *Builder) NeedsConfig() bool {
 	return b.needsConfig
 }
 
-// Compute a value. Return nil if it can't be computed
+// Confirm asks the user a yes or no question
+func (b *Builder) Confirm(request string) bool {
+	fmt.Print(request)
+	if b.assumeYes {
+		fmt.Println("y")
+		return true
+	}
+	yOrN := ""
+	fmt.Fscanf(b.input, "%s", &yOrN)
+	return strings.HasPrefix(strings.ToLower(yOrN), "y")
+}
+
+// answer returns the answer for fldName if one was given and the field applies to the app type
+func answer[V any](b *Builder, fldName string, ats mapset.Set[string]) ([]V, bool) {
+	a, ok := b.answers[fldName]
+	if !ok || !ats.Contains(b.at) {
+		return nil, false
+	}
+	switch v := a.(type) {
+	case V:
+		return []V{v}, true
+	case []V:
+		return v, true
+	}
+	panic(fmt.Sprintf("Warning: Answer for '%s' has unexpected type %T\n", fldName, a))
+}
+
+// Compute a value. Return nil if it can't be computed. A reset value is computed again but kept if that fails.
 func Compute[V comparable](b *Builder, strct any, fldName string, fn func() (V, error)) *V {
 	val := GetFieldValueByName[*V](strct, fldName)
 	ats := GetFieldTags(strct, fldName)
-	if val != nil {
+	if vals, ok := answer[V](b, fldName, ats); ok && len(vals) == 1 {
+		return &vals[0]
+	}
+	if val != nil && !b.resetting(fldName) {
 		return val
 	}
-	b.needsConfig = true
+	if val == nil {
+		b.needsConfig = true
+	}
 	if b.dryRun {
 		return val
 	}
@@ -154,7 +232,7 @@ func Request[V any](b *Builder, strct any, fldName string, def V, request string
 	} else {
 		vals = append(vals, *val)
 	}
-	res := RequestSliceRaw(b, vals, []V{def}, request, nil, ats)
+	res := RequestSliceRaw(b, fldName, vals, []V{def}, request, nil, ats)
 	if len(res) == 1 {
 		return &res[0]
 	}
@@ -165,14 +243,20 @@ func Request[V any](b *Builder, strct any, fldName string, def V, request string
 func RequestSlice[V any](b *Builder, strct any, fldName string, def []V, request string, subrequests []string) []V {
 	vals := GetFieldValueByName[[]V](strct, fldName)
 	ats := GetFieldTags(strct, fldName)
-	return RequestSliceRaw(b, vals, def, request, subrequests, ats)
+	return RequestSliceRaw(b, fldName, vals, def, request, subrequests, ats)
 }
 
-func RequestSliceRaw[V any](b *Builder, vals []V, def []V, request string, subrequests []string, ats mapset.Set[string]) []V {
-	if vals != nil {
+// RequestSliceRaw asks the user provide a slice of value for the field fldName whose current value is vals
+func RequestSliceRaw[V any](b *Builder, fldName string, vals []V, def []V, request string, subrequests []string, ats mapset.Set[string]) []V {
+	if answered, ok := answer[V](b, fldName, ats); ok {
+		return answered
+	}
+	if vals != nil && !b.editing(fldName) {
 		return vals
 	}
-	b.needsConfig = true
+	if vals == nil {
+		b.needsConfig = true
+	}
 	if b.dryRun {
 		return vals
 	}
@@ -196,6 +280,19 @@ func RequestSliceRaw[V any](b *Builder, vals []V, def []V, request string, subre
 	// Find the minimal number of expected responses.
 	minExpectedSubresponses := len(subrequests)
 
+	// When editing the current value becomes the default
+	editing := vals != nil
+	if editing {
+		if minExpectedSubresponses == 0 {
+			fmt.Printf("Current value: %v (hit enter to keep it)\n", vals[0])
+			def = vals
+			vals = nil
+		} else {
+			vals = removeEntries(b, vals, minExpectedSubresponses)
+			def = vals
+		}
+	}
+
 	var thisVals []V
 	fmt.Println(request)
 	for i := 0; true; i++ {
@@ -211,6 +308,9 @@ func RequestSliceRaw[V any](b *Builder, vals []V, def []V, request string, subre
 			yOrN := ""
 			count, _ := fmt.Fscanf(b.input, format, &yOrN)
 			if count == 0 {
+				if editing {
+					return def
+				}
 				return vals
 			}
 			if strings.HasPrefix(strings.ToLower(yOrN), "y") {
@@ -247,6 +347,32 @@ func RequestSliceRaw[V any](b *Builder, vals []V, def []V, request string, subre
 	return vals
 }
 
+// removeEntries lists the entries (each made of size values) of a slice and returns the ones the user doesn't remove
+func removeEntries[V any](b *Builder, vals []V, size int) []V {
+	fmt.Println("Current entries:")
+	for i := 0; i*size < len(vals); i++ {
+		fmt.Printf("  %d: %s\n", i, strings.Trim(fmt.Sprint(vals[i*size:min((i+1)*size, len(vals))]), "[]"))
+	}
+	fmt.Println("Enter the number of each entry to remove. Hit enter after each number and hit enter again when done.")
+	remove := mapset.NewSet[int]()
+	for {
+		var i int
+		count, _ := fmt.Fscanf(b.input, "%d", &i)
+		if count == 0 {
+			break
+		}
+		remove.Add(i)
+	}
+
+	kept := []V{}
+	for i := 0; i*size < len(vals); i++ {
+		if !remove.Contains(i) {
+			kept = append(kept, vals[i*size:min((i+1)*size, len(vals))]...)
+		}
+	}
+	return kept
+}
+
 // GetWorkDir returns the current working directory.
 func GetWorkDir() string {
 	wd, err := os.Getwd()
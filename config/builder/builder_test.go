@@ -2,11 +2,13 @@ package builder_test
 
 import (
 	"errors"
+	"io"
 	"os"
 	"slices"
 	"strings"
 	"testing"
 
+	mapset "github.com/deckarep/golang-set/v2"
 	"github.com/efarrer/gots/config/builder"
 	"github.com/stretchr/testify/require"
 )
@@ -105,6 +107,27 @@ func TestCompute(t *testing.T) {
 		require.Nil(t, result)
 		require.True(t, b.NeedsConfig())
 	})
+
+	t.Run("computes a reset value again", func(t *testing.T) {
+		value := govalue{Value: Ptr("old value")}
+		b := builder.New(os.Stdin, builder.AppTypeGo).Reset("Value")
+
+		result := builder.Compute(b, value, "Value", func() (string, error) { return "new value", nil })
+		require.Equal(t, "new value", *result)
+		require.False(t, b.NeedsConfig())
+
+		// The value is kept if it can't be computed
+		result = builder.Compute(b, value, "Value", func() (string, error) { return "", errors.New("some error") })
+		require.Equal(t, "old value", *result)
+	})
+
+	t.Run("doesn't compute an edited value again", func(t *testing.T) {
+		value := govalue{Value: Ptr("old value")}
+		b := builder.New(os.Stdin, builder.AppTypeGo).Edit()
+
+		result := builder.Compute(b, value, "Value", func() (string, error) { return "new value", nil })
+		require.Equal(t, "old value", *result)
+	})
 }
 
 func TestRequest(t *testing.T) {
@@ -308,3 +331,138 @@ func TestGetWorkDir(t *testing.T) {
 func TestGetCmd(t *testing.T) {
 	require.Empty(t, builder.GetCmd())
 }
+
+func TestAnswer(t *testing.T) {
+	type govalues struct {
+		Int    *int     `gots:"go"`
+		String *string  `gots:"go"`
+		Slice  []string `gots:"go"`
+	}
+	type novalue struct {
+		Value *string `gots:"nope"`
+	}
+
+	t.Run("Request uses the answer instead of the input", func(t *testing.T) {
+		b := builder.New(strings.NewReader("ignored"), builder.AppTypeGo).Answer("String", "answered")
+
+		result := builder.Request(b, govalues{}, "String", "", "")
+
+		require.Equal(t, "answered", *result)
+	})
+
+	t.Run("Request answers replace existing values", func(t *testing.T) {
+		b := builder.New(os.Stdin, builder.AppTypeGo).Answer("Int", 443)
+
+		result := builder.Request(b, govalues{Int: Ptr(80)}, "Int", 0, "")
+
+		require.Equal(t, 443, *result)
+	})
+
+	t.Run("RequestSlice uses the answer", func(t *testing.T) {
+		b := builder.New(os.Stdin, builder.AppTypeGo).Answer("Slice", []string{"a", "b"})
+
+		result := builder.RequestSlice(b, govalues{}, "Slice", []string{}, "", []string{"Arg %d: "})
+
+		require.Equal(t, []string{"a", "b"}, result)
+	})
+
+	t.Run("Compute uses the answer", func(t *testing.T) {
+		b := builder.New(os.Stdin, builder.AppTypeGo).Answer("String", "answered")
+
+		result := builder.Compute(b, govalues{}, "String", func() (string, error) { return "computed", nil })
+
+		require.Equal(t, "answered", *result)
+	})
+
+	t.Run("respects AppType", func(t *testing.T) {
+		b := builder.New(os.Stdin, builder.AppTypeGo).Answer("Value", "answered")
+
+		result := builder.Request[string](b, novalue{}, "Value", "", "")
+
+		require.Nil(t, result)
+	})
+}
+
+func TestConfirm(t *testing.T) {
+	require.True(t, builder.New(strings.NewReader("yes"), builder.AppTypeGo).Confirm(""))
+	require.False(t, builder.New(strings.NewReader("no"), builder.AppTypeGo).Confirm(""))
+	require.True(t, builder.New(strings.NewReader("no"), builder.AppTypeGo).AssumeYes().Confirm(""))
+}
+
+// terminal reads like os.Stdin, which can't unread the newline ending each answer
+func terminal(input string) io.Reader {
+	return struct{ io.Reader }{strings.NewReader(input)}
+}
+
+func TestEdit(t *testing.T) {
+	type govalues struct {
+		Int   *int     `gots:"go"`
+		Bool  *bool    `gots:"go"`
+		Slice []string `gots:"go"`
+	}
+
+	t.Run("Request keeps the current value on enter", func(t *testing.T) {
+		b := builder.New(terminal("\n"), builder.AppTypeGo).Edit()
+
+		result := builder.Request(b, govalues{Int: Ptr(80)}, "Int", 8080, "")
+
+		require.Equal(t, 80, *result)
+		require.False(t, b.NeedsConfig())
+	})
+
+	t.Run("Request replaces the current value", func(t *testing.T) {
+		b := builder.New(terminal("443\n"), builder.AppTypeGo).Edit()
+
+		result := builder.Request(b, govalues{Int: Ptr(80)}, "Int", 8080, "")
+
+		require.Equal(t, 443, *result)
+	})
+
+	t.Run("Request keeps the current bool on enter", func(t *testing.T) {
+		b := builder.New(terminal("\n"), builder.AppTypeGo).Edit()
+
+		result := builder.Request(b, govalues{Bool: Ptr(true)}, "Bool", false, "")
+
+		require.True(t, *result)
+	})
+
+	t.Run("RequestSlice removes and adds entries", func(t *testing.T) {
+		b := builder.New(terminal("1\n\nd\n\n"), builder.AppTypeGo).Edit()
+
+		result := builder.RequestSlice(b, govalues{Slice: []string{"a", "b", "c"}}, "Slice", []string{}, "", []string{"Arg %d: "})
+
+		require.Equal(t, []string{"a", "c", "d"}, result)
+	})
+
+	t.Run("RequestSlice removes entries made of several values", func(t *testing.T) {
+		b := builder.New(terminal("0\n\n\n"), builder.AppTypeGo).Edit()
+
+		result := builder.RequestSlice(b, govalues{Slice: []string{"a", "b", "c", "d"}}, "Slice", []string{}, "",
+			[]string{"Key %d: ", "Value %d: "})
+
+		require.Equal(t, []string{"c", "d"}, result)
+	})
+
+	t.Run("RequestSlice can remove every entry", func(t *testing.T) {
+		b := builder.New(terminal("0\n1\n\n\n"), builder.AppTypeGo).Edit()
+
+		result := builder.RequestSlice(b, govalues{Slice: []string{"a", "b"}}, "Slice", []string{"default"}, "", []string{"Arg %d: "})
+
+		require.Equal(t, []string{}, result)
+	})
+
+	t.Run("Reset only requests the named fields", func(t *testing.T) {
+		b := builder.New(terminal("443\n"), builder.AppTypeGo).Reset("Int")
+
+		require.Equal(t, 443, *builder.Request(b, govalues{Int: Ptr(80)}, "Int", 8080, ""))
+		require.Equal(t, []string{"a"}, builder.RequestSlice(b, govalues{Slice: []string{"a"}}, "Slice", []string{}, "", []string{"Arg %d: "}))
+	})
+
+	t.Run("Reset requests the nested fields", func(t *testing.T) {
+		b := builder.New(terminal("\nb\n\n"), builder.AppTypeGo).Reset("Slice")
+		ats := mapset.NewSet(builder.AppTypeGo)
+
+		require.Equal(t, []string{"a", "b"}, builder.RequestSliceRaw(b, "Slice.nested", []string{"a"}, []string{}, "", []string{"Arg %d: "}, ats))
+		require.Equal(t, []string{"a"}, builder.RequestSliceRaw(b, "Other.nested", []string{"a"}, []string{}, "", []string{"Arg %d: "}, ats))
+	})
+}
@@ -1,11 +1,18 @@
 package compute
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/engine"
+	"github.com/efarrer/gots/run"
 )
 
 // ComputeGoCompilePath returns the path to compile the Go executable
@@ -56,3 +63,79 @@ func GetCmd() (string, error) {
 }
 
 var Getwd = os.Getwd
+
+// GoVersion returns the Go version required by the go.mod in dir. The toolchain directive is used if there is one
+// otherwise the go directive.
+func GoVersion(dir string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+
+	version := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "toolchain":
+			return strings.TrimPrefix(fields[1], "go"), nil
+		case "go":
+			version = fields[1]
+		}
+	}
+	if version == "" {
+		return "", errors.New(fmt.Sprintf("No go version in %s", filepath.Join(dir, "go.mod")))
+	}
+	return version, nil
+}
+
+// NormalizePlatform converts the OS and architecture reported by the container engine into a platform (e.g. linux/arm64)
+func NormalizePlatform(osType string, arch string) string {
+	switch arch {
+	case "x86_64":
+		arch = "amd64"
+	case "aarch64":
+		arch = "arm64"
+	case "armv7l":
+		arch = "arm/v7"
+	case "armv6l":
+		arch = "arm/v6"
+	}
+	return osType + "/" + arch
+}
+
+// infoTimeout limits how long getting the container engine's platform can take
+var infoTimeout = time.Minute
+
+// ComputeTargetPlatform returns the platform (e.g. linux/arm64) of the container engine
+func ComputeTargetPlatform(e engine.Engine) func() (string, error) {
+	return func() (string, error) {
+		// A remote engine that can't be reached shouldn't hang the configuration
+		ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+		defer cancel()
+		result, err := run.Run(ctx, run.Cmd{Name: e.Name(), Args: e.PlatformInfoArgs(), Env: e.Env()})
+		if err != nil {
+			return "", fmt.Errorf("Unable to get %s info %w\n%s", e.Name(), err, result.Stderr)
+		}
+		fields := strings.Fields(result.Stdout)
+		if len(fields) != 2 {
+			return "", errors.New(fmt.Sprintf("Unexpected %s info output %s", e.Name(), result.Stdout))
+		}
+		return NormalizePlatform(fields[0], fields[1]), nil
+	}
+}
+
+// ComputeRootless returns true if the container engine (podman) runs without root
+func ComputeRootless(e engine.Engine) func() (bool, error) {
+	return func() (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), infoTimeout)
+		defer cancel()
+		result, err := run.Run(ctx, run.Cmd{Name: e.Name(), Args: e.RootlessInfoArgs(), Env: e.Env()})
+		if err != nil {
+			return false, fmt.Errorf("Unable to get %s info %w\n%s", e.Name(), err, result.Stderr)
+		}
+		return strconv.ParseBool(strings.TrimSpace(result.Stdout))
+	}
+}
@@ -0,0 +1,67 @@
+package compute_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efarrer/gots/config/compute"
+	"github.com/efarrer/gots/engine"
+	"github.com/efarrer/gots/run"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoVersion(t *testing.T) {
+	writeGoMod := func(t *testing.T, contents string) string {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte(contents), 0644))
+		return dir
+	}
+
+	t.Run("go directive", func(t *testing.T) {
+		version, err := compute.GoVersion(writeGoMod(t, "module example.com/app\n\ngo 1.25\n"))
+		require.NoError(t, err)
+		require.Equal(t, "1.25", version)
+	})
+
+	t.Run("toolchain directive", func(t *testing.T) {
+		version, err := compute.GoVersion(writeGoMod(t, "module example.com/app\n\ngo 1.24\n\ntoolchain go1.25.3\n"))
+		require.NoError(t, err)
+		require.Equal(t, "1.25.3", version)
+	})
+
+	t.Run("no go directive", func(t *testing.T) {
+		_, err := compute.GoVersion(writeGoMod(t, "module example.com/app\n"))
+		require.Error(t, err)
+	})
+
+	t.Run("no go.mod", func(t *testing.T) {
+		_, err := compute.GoVersion(t.TempDir())
+		require.Error(t, err)
+	})
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	require.Equal(t, "linux/amd64", compute.NormalizePlatform("linux", "x86_64"))
+	require.Equal(t, "linux/arm64", compute.NormalizePlatform("linux", "aarch64"))
+	require.Equal(t, "linux/arm/v7", compute.NormalizePlatform("linux", "armv7l"))
+	require.Equal(t, "linux/riscv64", compute.NormalizePlatform("linux", "riscv64"))
+}
+
+func TestComputeRootless(t *testing.T) {
+	output := "true\n"
+	fake := &run.Fake{Handler: func(cmd run.Cmd) (run.Result, error) {
+		return run.Result{Stdout: output}, nil
+	}}
+	defer run.SetDefault(fake)()
+
+	rootless, err := compute.ComputeRootless(engine.New(engine.Podman))()
+	require.NoError(t, err)
+	require.True(t, rootless)
+	require.Equal(t, []string{"podman info --format {{.Host.Security.Rootless}}"}, fake.Lines())
+
+	output = "false\n"
+	rootless, err = compute.ComputeRootless(engine.New(engine.Podman))()
+	require.NoError(t, err)
+	require.False(t, rootless)
+}
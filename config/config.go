@@ -1,16 +1,24 @@
 package config
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/efarrer/gots/config/builder"
 	"github.com/efarrer/gots/config/compute"
+	"github.com/efarrer/gots/engine"
+	"github.com/efarrer/gots/launcher"
 )
 
 //go:embed Dockerfile.template
@@ -22,11 +30,17 @@ var serveConfigTemplate string
 //go:embed docker-compose.yaml.template
 var dockerComposeTemplate string
 
-//go:embed gots-run.template
-var gotsRunTemplate string
+//go:embed gots.service.template
+var serviceUnitTemplate string
+
+//go:embed tailscaled.service.template
+var tailscaledUnitTemplate string
 
 const configPath = "./.gots"
 
+// DefaultGoRuntimeImage is the default image that executables built in Docker run in
+const DefaultGoRuntimeImage = "gcr.io/distroless/static-debian12"
+
 // Deref safely derefs a pointer. For nil returns the zero value
 func Deref[A any](pa *A) A {
 	if pa == nil {
@@ -36,6 +50,15 @@ func Deref[A any](pa *A) A {
 	return *pa
 }
 
+// NilIfZero returns nil if pa points to the zero value
+func NilIfZero[A comparable](pa *A) *A {
+	var zero A
+	if pa == nil || *pa == zero {
+		return nil
+	}
+	return pa
+}
+
 // Volume represents a docker volume
 type Volume struct {
 	DockerDir string
@@ -65,6 +88,55 @@ func StringsToVolumes(strs []string) []Volume {
 	return ret
 }
 
+// Service is an additional container (e.g. a database) that runs alongside the application
+type Service struct {
+	Name  string
+	Image string
+	// Hostname is the service's own tailnet hostname. If empty the service shares the application's node.
+	Hostname string `json:"Hostname,omitempty"`
+	// Port is the TCP port that is served over HTTPS on the service's own node (0 for none)
+	Port          int      `json:"Port,omitempty"`
+	Funnel        bool     `json:"Funnel,omitempty"`
+	DockerVolumes []Volume `json:"DockerVolumes,omitempty"`
+	Env           []EnvVar `json:"Env,omitempty"`
+}
+
+// ServicesToStrings flattens services into the strings requested by the configuration wizard
+func ServicesToStrings(ss []Service) []string {
+	if ss == nil {
+		return nil
+	}
+	ret := []string{}
+	for _, s := range ss {
+		hostname := s.Hostname
+		if hostname == "" {
+			hostname = "-"
+		}
+		ret = append(ret, s.Name, s.Image, hostname, strconv.Itoa(s.Port))
+	}
+	return ret
+}
+
+// StringsToServices converts the strings requested by the configuration wizard into services
+func StringsToServices(strs []string) ([]Service, error) {
+	if strs == nil {
+		return nil, nil
+	}
+	ret := []Service{}
+	for i := 0; i+3 < len(strs); i += 4 {
+		port, err := strconv.Atoi(strs[i+3])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid port %s for service %s\n", strs[i+3], strs[i])
+		}
+		hostname := strs[i+2]
+		if hostname == "-" {
+			hostname = ""
+		}
+		ret = append(ret, Service{Name: strs[i], Image: strs[i+1], Hostname: hostname, Port: port})
+	}
+	return ret, nil
+}
+
 // GetNilFieldNames iterates over a struct and returns the names of fields
 // that are not nil (for pointer types) or are not their zero value (for non-pointer types).
 func GetNilFieldNames(s interface{}) []string {
@@ -113,38 +185,315 @@ func FilterSlice[T any](slice []T, filter func(T) bool) []T {
 
 // Config the gots configuration
 type Config struct {
+	Version                  int `json:"Version"` // Schema version (see CurrentVersion)
 	Type                     string
-	DockerImage              *string  `gots:"go,dockerimage,dockerfile" json:"DockerImage,omitempty"`
-	DockerHostname           *string  `gots:"go,dockerimage,dockerfile"" json:"DockerHostname,omitempty"`
-	ExecName                 *string  `gots:"go" json:"ExecName,omitempty"`
-	ExecArgs                 []string `gots:"go" json:"ExecArgs,omitempty"`
-	DeprecatedCompileCommand []string `json:"CompileCommand,omitempty"` // Deprecated
-	GoCompilePath            *string  `gots:"go" json:"GoCompilePath,omitempty"`
-	Port                     *int     `gots:"go,dockerimage,dockerfile" json:"Port,omitempty"`
-	Funnel                   *bool    `gots:"go,dockerimage,dockerfile" json:"Funnel,omitempty"`
-	DockerVolumes            []Volume `gots:"go,dockerimage,dockerfile" json:"DockerVolumes"`
-	WorkDir                  *string  `gots:"go,dockerimage,dockerfile" json:"WorkDir,omitempty"`
+	DockerImage              *string    `gots:"go,dockerimage,dockerfile" json:"DockerImage,omitempty"`
+	DockerHostname           *string    `gots:"go,dockerimage,dockerfile" json:"DockerHostname,omitempty"`
+	ExecName                 *string    `gots:"go" json:"ExecName,omitempty"`
+	ExecArgs                 []string   `gots:"go" json:"ExecArgs,omitempty"`
+	DeprecatedCompileCommand []string   `json:"CompileCommand,omitempty"` // Deprecated
+	GoCompilePath            *string    `gots:"go" json:"GoCompilePath,omitempty"`
+	GoDockerBuild            *bool      `gots:"go,optional" json:"GoDockerBuild,omitempty"`
+	GoRuntimeImage           *string    `gots:"go,optional" json:"GoRuntimeImage,omitempty"`
+	GoTsnet                  *bool      `gots:"go,optional" json:"GoTsnet,omitempty"`
+	Backend                  *string    `gots:"go,optional" json:"Backend,omitempty"`
+	Port                     *int       `gots:"go,dockerimage,dockerfile" json:"Port,omitempty"`
+	Funnel                   *bool      `gots:"go,dockerimage,dockerfile" json:"Funnel,omitempty"`
+	DockerVolumes            []Volume   `gots:"go,dockerimage,dockerfile" json:"DockerVolumes"`
+	WorkDir                  *string    `gots:"go,dockerimage,dockerfile" json:"WorkDir,omitempty"`
+	Engine                   *string    `gots:"go,dockerimage,dockerfile,optional" json:"Engine,omitempty"`
+	EngineRootless           *bool      `gots:"go,dockerimage,dockerfile,optional" json:"EngineRootless,omitempty"`
+	DockerHost               *string    `gots:"go,dockerimage,dockerfile,optional" json:"DockerHost,omitempty"`
+	TargetPlatform           *string    `gots:"go,dockerimage,dockerfile,optional" json:"TargetPlatform,omitempty"`
+	Services                 []Service  `gots:"go,dockerimage,dockerfile,optional" json:"Services,omitempty"`
+	Handlers                 []Handler  `gots:"go,dockerimage,dockerfile,optional" json:"Handlers,omitempty"`
+	Env                      []EnvVar   `gots:"go,dockerimage,dockerfile,optional" json:"Env,omitempty"`
+	EnvFiles                 []string   `gots:"go,dockerimage,dockerfile,optional" json:"EnvFiles,omitempty"`
+	TailscaleTags            []string   `gots:"go,dockerimage,dockerfile,optional" json:"TailscaleTags,omitempty"`
+	TailscaleEphemeral       *bool      `gots:"go,dockerimage,dockerfile,optional" json:"TailscaleEphemeral,omitempty"`
+	TailscaleAuthOnce        *bool      `gots:"go,dockerimage,dockerfile,optional" json:"TailscaleAuthOnce,omitempty"`
+	TailscaleOAuth           *bool      `gots:"go,dockerimage,dockerfile,optional" json:"TailscaleOAuth,omitempty"`
+	HealthCheck              *string    `gots:"go,dockerimage,dockerfile,optional" json:"HealthCheck,omitempty"`
+	HealthCheckInterval      *string    `gots:"go,dockerimage,dockerfile,optional" json:"HealthCheckInterval,omitempty"`
+	HealthCheckRetries       *int       `gots:"go,dockerimage,dockerfile,optional" json:"HealthCheckRetries,omitempty"`
+	RestartPolicy            *string    `gots:"go,dockerimage,dockerfile,optional" json:"RestartPolicy,omitempty"`
+	Resources                *Resources `gots:"go,dockerimage,dockerfile,optional" json:"Resources,omitempty"`
+	Registry                 *string    `gots:"go,dockerfile,optional" json:"Registry,omitempty"`
+}
+
+// Node is a tailnet node that is run as a tailscale sidecar container
+type Node struct {
+	Hostname string
+	// StateDir is the host directory that holds the node's tailscale state. Empty for ephemeral nodes whose state is
+	// only kept in memory.
+	StateDir string
+	// ServeConfig is the name of the node's generated serve config. Empty if the node doesn't serve anything.
+	ServeConfig string
+	// Files are the host paths that the node serves
+	Files []string
+	// serve is the configuration used to generate the node's serve config
+	serve Config
+}
+
+// Nodes returns the tailnet nodes for the application and each service that has its own hostname
+func (c Config) Nodes() []Node {
+	workDir := Deref(c.WorkDir)
+	nodes := []Node{{
+		Hostname:    Deref(c.DockerHostname),
+		StateDir:    workDir + "/.tailscale",
+		ServeConfig: "serve.config",
+		Files:       c.ServedFiles(),
+		serve:       c,
+	}}
+	for _, svc := range c.Services {
+		if svc.Hostname == "" {
+			continue
+		}
+		node := Node{
+			Hostname: svc.Hostname,
+			StateDir: workDir + "/.tailscale-" + svc.Hostname,
+			serve:    Config{Port: &svc.Port, Funnel: &svc.Funnel},
+		}
+		if svc.Port != 0 {
+			node.ServeConfig = "serve." + svc.Hostname + ".config"
+		}
+		nodes = append(nodes, node)
+	}
+	if Deref(c.TailscaleEphemeral) {
+		for i := range nodes {
+			nodes[i].StateDir = ""
+		}
+	}
+	return nodes
+}
+
+// ServiceNode returns the hostname of the tailnet node that the service runs on
+func (c Config) ServiceNode(svc Service) string {
+	if svc.Hostname != "" {
+		return svc.Hostname
+	}
+	return Deref(c.DockerHostname)
 }
 
 func (c Config) GoCompilePathSafe() string {
 	return Deref(c.GoCompilePath)
 }
 
-// Load loads the .gots (if it exists)
-func Load() *Config {
-	file, err := os.Open(configPath)
+// GoBuildsInDocker returns true if the Go executable is built in Docker instead of on the host
+func (c Config) GoBuildsInDocker() bool {
+	return c.Type == builder.AppTypeGo && Deref(c.GoDockerBuild)
+}
+
+// GoBuildImage returns the golang image, matching the version in the WorkDir's go.mod, used to build the executable
+// in Docker
+func (c Config) GoBuildImage() (string, error) {
+	version, err := compute.GoVersion(Deref(c.WorkDir))
+	if err != nil {
+		return "", fmt.Errorf("Unable to get the Go version %w", err)
+	}
+	return "golang:" + version, nil
+}
+
+// GoRuntimeImageSafe returns the image that the executable built in Docker runs in
+func (c Config) GoRuntimeImageSafe() string {
+	if c.GoRuntimeImage == nil {
+		return DefaultGoRuntimeImage
+	}
+	return *c.GoRuntimeImage
+}
+
+// ContainerEngine returns the engine used to build and run the application. Configurations without an Engine use the
+// installed one like the configuration wizard does.
+func (c Config) ContainerEngine() engine.Engine {
+	name := Deref(c.Engine)
+	if name == "" {
+		name, _ = engine.Detect()
+	}
+	return engine.New(name).WithHost(Deref(c.DockerHost))
+}
+
+// Remote returns true if the application is deployed to a remote Docker host
+func (c Config) Remote() bool {
+	return c.ContainerEngine().Remote()
+}
+
+// UserspaceNetworking returns true if the tailscale sidecars must use userspace networking. Podman is assumed to be
+// rootless if EngineRootless wasn't computed.
+func (c Config) UserspaceNetworking() bool {
+	return c.ContainerEngine().UserspaceNetworking(c.EngineRootless == nil || *c.EngineRootless)
+}
+
+// GoEnv returns the environment variables that cross-compile the Go executable for the TargetPlatform
+func (c Config) GoEnv() []string {
+	if c.TargetPlatform == nil {
+		return nil
+	}
+	parts := strings.Split(*c.TargetPlatform, "/")
+	env := []string{"GOOS=" + parts[0]}
+	if len(parts) > 1 {
+		env = append(env, "GOARCH="+parts[1])
+	}
+	if len(parts) > 2 && parts[1] == "arm" {
+		env = append(env, "GOARM="+strings.TrimPrefix(parts[2], "v"))
+	}
+	return env
+}
+
+// Cmd returns the executable and its arguments
+func (c Config) Cmd() []string {
+	return append([]string{"/bin/" + Deref(c.ExecName)}, c.ExecArgs...)
+}
+
+// Exists returns true if there is a configuration file
+func Exists() bool {
+	for _, path := range configPaths {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Load loads the configuration file (if it exists) in the format matching its extension (see Path). A malformed
+// configuration is an error rather than being treated as a new configuration.
+func Load() (*Config, error) {
+	path, err := Path()
 	if err != nil {
-		return &Config{}
+		return nil, err
 	}
-	defer file.Close()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s %w\n", path, err)
+	}
+
+	cfg, err := ParseAs(FormatOf(path), data)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
 
+// Parse parses the contents of a JSON .gots file. Errors include the line and column of the problem.
+func Parse(data []byte) (*Config, error) {
 	cfg := Config{}
-	err = json.NewDecoder(file).Decode(&cfg)
+	err := json.Unmarshal(data, &cfg)
 	if err != nil {
-		return &Config{}
+		var offset int64 = -1
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &syntaxErr) {
+			offset = syntaxErr.Offset
+		} else if errors.As(err, &typeErr) {
+			offset = typeErr.Offset
+		}
+		if offset < 0 {
+			return nil, fmt.Errorf("%w\n", err)
+		}
+		// The offset is just past the byte that caused the error
+		line, column := position(data, max(offset-1, 0))
+		return nil, fmt.Errorf("line %d column %d: %w\n", line, column, err)
+	}
+	return &cfg, nil
+}
+
+// position converts a byte offset into data to a 1 based line and column
+func position(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	line, column := 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
 	}
+	return line, column
+}
 
-	return &cfg
+// Backup copies the configuration file to <file>.bak returning the path of the backup
+func Backup() (string, error) {
+	path, err := Path()
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %s %w\n", path, err)
+	}
+	backupPath := path + ".bak"
+	err = os.WriteFile(backupPath, data, 0644)
+	if err != nil {
+		return "", fmt.Errorf("Unable to write %s %w\n", backupPath, err)
+	}
+	return backupPath, nil
+}
+
+// LoadAnswers loads a configuration file in the .gots format (JSON, YAML, or TOML based on the extension) whose
+// fields are used as answers for the configuration wizard
+func LoadAnswers(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read answers file %s %w\n", path, err)
+	}
+
+	cfg, err := ParseAs(FormatOf(path), data)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse answers file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Answers returns the fields that are set in the configuration in the form expected by builder.Builder.Answer
+func (c *Config) Answers() map[string]any {
+	answers := map[string]any{}
+	val := reflect.ValueOf(*c)
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		name := val.Type().Field(i).Name
+		if builder.GetFieldTags(c, name).Cardinality() == 0 {
+			continue
+		}
+		if field.IsNil() {
+			continue
+		}
+		if field.Kind() == reflect.Ptr {
+			answers[name] = field.Elem().Interface()
+		} else {
+			answers[name] = field.Interface()
+		}
+	}
+	// Resources is requested field by field
+	if c.Resources != nil {
+		val := reflect.ValueOf(*c.Resources)
+		for i := 0; i < val.NumField(); i++ {
+			if field := val.Field(i); !field.IsNil() {
+				answers[val.Type().Field(i).Name] = field.Elem().Interface()
+			}
+		}
+		delete(answers, "Resources")
+	}
+	// DockerVolumes, Services, Handlers, and Env are requested as a []string
+	if c.Env != nil {
+		answers["Env"] = EnvToStrings(c.Env)
+	}
+	if c.Handlers != nil {
+		answers["Handlers"] = HandlersToStrings(c.Handlers)
+	}
+	if c.DockerVolumes != nil {
+		answers["DockerVolumes"] = VolumesToStrings(c.DockerVolumes)
+	}
+	if c.Services != nil {
+		answers["Services"] = ServicesToStrings(c.Services)
+		serviceAnswers(answers, c.Services)
+	}
+	return answers
 }
 
 // ValidateComplete validates that the configuration is complete
@@ -153,8 +502,9 @@ func (c *Config) ValidateComplete() bool {
 	names := FilterSlice(
 		GetNilFieldNames(*c),
 		func(field string) bool {
-			// Ignore fields that aren't for this app type
-			if !builder.GetFieldTags(c, field).Contains(c.Type) {
+			tags := builder.GetFieldTags(c, field)
+			// Ignore fields that aren't for this app type or that are optional
+			if !tags.Contains(c.Type) || tags.Contains(builder.TagOptional) {
 				return true
 			}
 			return false
@@ -163,58 +513,91 @@ func (c *Config) ValidateComplete() bool {
 	return len(names) == 0
 }
 
-// Migrate performs any migrations that are needed
-func (c *Config) Migrate() {
-	if len(c.DeprecatedCompileCommand) > 0 {
-		c.GoCompilePath = &c.DeprecatedCompileCommand[len(c.DeprecatedCompileCommand)-1]
-		c.DeprecatedCompileCommand = nil
-	}
-	if c.Type == "" {
-		c.Type = builder.AppTypeGo
-	}
-
-	// For go both the DockerImage and the DockerHostname are the same as the exec name
-	if c.Type == builder.AppTypeGo && c.DockerImage == nil {
-		c.DockerImage = c.ExecName
-	}
-	if c.Type == builder.AppTypeGo && c.DockerHostname == nil {
-		c.DockerHostname = c.ExecName
-	}
-	if c.Type == "image" {
-		c.Type = builder.AppTypeDockerImage
-	}
-}
-
-// RequestMissingConfiguration prompts the user for missing configuration parameters
-func (c *Config) RequestMissingConfiguration() error {
+// RequestMissingConfiguration prompts the user for missing configuration parameters using the builder b
+func (c *Config) RequestMissingConfiguration(b *builder.Builder) error {
 	// Grab the original configuration to see if anything changed
 	origConfiguration := *c
 
-	b := builder.New(os.Stdin, c.Type)
-
 	c.ExecName = builder.Compute(b, c, "ExecName", compute.GetCmd)
 	c.ExecName = builder.Request[string](b, c, "ExecName", "", "Enter the name of the executable: ")
 	// For go both the DockerImage and the DockerHostname are the same as the exec name
 	if c.Type == builder.AppTypeGo {
 		c.DockerHostname = c.ExecName
 		c.DockerImage = c.ExecName
+	} else {
+		c.DockerHostname = builder.Request(b, c, "DockerHostname", "", "Enter the hostname to use in the docker container: ")
+		c.DockerImage = builder.Request(b, c, "DockerImage", "", "Enter the name of the docker image to execute: ")
 	}
-	c.DockerHostname = builder.Request(b, c, "DockerHostname", "", "Enter the hostname to use in the docker container: ")
-	c.DockerImage = builder.Request(b, c, "DockerImage", "", "Enter the name of the docker image to execute: ")
 	c.WorkDir = builder.Compute(b, c, "WorkDir", compute.Getwd)
+	c.Backend = builder.Request(b, c, "Backend", BackendDocker, "Run the executable in docker or as a systemd user service on this host, docker or systemd (default docker): ")
+	// The container settings don't apply to systemd services
+	if !c.Systemd() {
+		c.Engine = builder.Compute(b, c, "Engine", engine.Detect)
+		c.Engine = builder.Request(b, c, "Engine", engine.Docker, "Enter the container engine, docker or podman (default docker): ")
+		c.DockerHost = builder.Request(b, c, "DockerHost", "", "Enter the docker context or host (e.g. ssh://user@server) to deploy to (hit enter for this machine): ")
+		if c.ContainerEngine().Name() == engine.Podman {
+			c.EngineRootless = builder.Compute(b, c, "EngineRootless", compute.ComputeRootless(c.ContainerEngine()))
+		}
+		c.TargetPlatform = builder.Compute(b, c, "TargetPlatform", compute.ComputeTargetPlatform(c.ContainerEngine()))
+		c.TargetPlatform = builder.Request(b, c, "TargetPlatform", "linux/amd64", "Enter the platform of the Docker host (default linux/amd64): ")
+		c.Registry = builder.Request(b, c, "Registry", "", "Enter the registry to push the image to, e.g. ghcr.io/me or localhost:5000 (hit enter for none): ")
+	}
 	c.GoCompilePath = builder.Compute(b, c, "GoCompilePath", compute.ComputeGoCompilePath(c.ExecName))
 	c.GoCompilePath = builder.Request(b, c, "GoCompilePath", "", "Enter the path to the directory that contains the main.go (e.g. ./cmd/foo): ")
+	if !c.Systemd() {
+		c.GoDockerBuild = builder.Request(b, c, "GoDockerBuild", false, "Should the executable be built in Docker (instead of with the Go compiler on this host)? (y/n): ")
+	}
+	if Deref(c.GoDockerBuild) {
+		c.GoRuntimeImage = builder.Request(b, c, "GoRuntimeImage", DefaultGoRuntimeImage,
+			fmt.Sprintf("Enter the image to run the executable in, scratch is also supported (default %s): ", DefaultGoRuntimeImage))
+	}
+	c.GoTsnet = builder.Request(b, c, "GoTsnet", false, "Should the executable join the tailnet itself with tsnet (instead of a separate tailscale container or tailscaled)? (y/n): ")
 	c.Port = builder.Request[int](b, c, "Port", 80, "What TCP port is used by the application (default 80): ")
 	c.ExecArgs = builder.RequestSlice(b, c, "ExecArgs", []string{},
 		fmt.Sprintf("Enter the command line arguments to pass to \"%s\". Hit enter after each argument.\n", Deref(c.ExecName)),
 		[]string{"Arg %d: "},
 	)
 	c.Funnel = builder.Request(b, c, "Funnel", false, "Should a Tailscale funnel be started? (y/n): ")
+	c.TailscaleTags = builder.RequestSlice(b, c, "TailscaleTags", []string{},
+		"Enter the ACL tags for the Tailscale nodes (e.g. tag:server). Hit enter after each tag or just hit enter for none.\n",
+		[]string{"Tag %d: "},
+	)
+	c.TailscaleEphemeral = builder.Request(b, c, "TailscaleEphemeral", false, "Should the Tailscale nodes be ephemeral (removed from the tailnet when they go offline)? (y/n): ")
+	c.TailscaleAuthOnce = builder.Request(b, c, "TailscaleAuthOnce", false, "Should TS_AUTHKEY only be used when a node isn't already logged in? (y/n): ")
+	if len(c.TailscaleTags) > 0 {
+		c.TailscaleOAuth = builder.Request(b, c, "TailscaleOAuth", false, "Is TS_AUTHKEY an OAuth client secret (tskey-client-...)? (y/n): ")
+	}
+	c.HealthCheck = builder.Request(b, c, "HealthCheck", "", fmt.Sprintf("Enter the health check, an HTTP path (e.g. /healthz) or %s to just connect to port %d (hit enter for none): ", HealthCheckTCP, Deref(c.Port)))
+	if c.HealthCheckSafe() != "" {
+		c.HealthCheckInterval = builder.Request(b, c, "HealthCheckInterval", DefaultHealthCheckInterval,
+			fmt.Sprintf("Enter the time between health checks (default %s): ", DefaultHealthCheckInterval))
+		c.HealthCheckRetries = builder.Request(b, c, "HealthCheckRetries", DefaultHealthCheckRetries,
+			fmt.Sprintf("How many health checks can fail before the application is unhealthy (default %d): ", DefaultHealthCheckRetries))
+	}
+	c.RestartPolicy = builder.Request(b, c, "RestartPolicy", RestartUnlessStopped,
+		fmt.Sprintf("When should the application be restarted, %s (default %s): ", strings.Join(RestartPolicies, ", "), RestartUnlessStopped))
 
-	// DockerVolumes is special in that we want to use a struct not []string so the docker/host paths are unambiguous
+	// Resources is requested field by field
 	{
+		resources := Deref(c.Resources)
+		// Hitting enter leaves a limit unset rather than saving an empty one
+		resources.Memory = NilIfZero(builder.Request(b, &resources, "Memory", "", "Enter the application's memory limit, e.g. 512m or 2g (hit enter for no limit): "))
+		resources.CPUs = NilIfZero(builder.Request(b, &resources, "CPUs", "", "Enter the number of CPUs the application can use, e.g. 0.5 or 2 (hit enter for no limit): "))
+		resources.NoFile = NilIfZero(builder.Request(b, &resources, "NoFile", 0, "Enter the maximum number of files the application can open (default 0 for no limit): "))
+		resources.StopTimeout = NilIfZero(builder.Request(b, &resources, "StopTimeout", "", "Enter how long the application has to stop before it's killed, e.g. 30s (hit enter for the default): "))
+		c.Resources = nil
+		if resources.Memory != nil || resources.CPUs != nil || resources.NoFile != nil || resources.StopTimeout != nil {
+			c.Resources = &resources
+		}
+	}
+
+	// DockerVolumes is special in that we want to use a struct not []string so the docker/host paths are unambiguous
+	if c.Systemd() {
+		// A host process doesn't need volumes
+		c.DockerVolumes = []Volume{}
+	} else {
 		ats := builder.GetFieldTags(c, "DockerVolumes")
-		c.DockerVolumes = StringsToVolumes(builder.RequestSliceRaw(b, VolumesToStrings(c.DockerVolumes), []string{},
+		c.DockerVolumes = StringsToVolumes(builder.RequestSliceRaw(b, "DockerVolumes", VolumesToStrings(c.DockerVolumes), []string{},
 			fmt.Sprintf("Enter the volumes to mount in the Docker container\n"),
 			[]string{
 				"Docker dir (absolute path) %d: ",
@@ -224,6 +607,61 @@ func (c *Config) RequestMissingConfiguration() error {
 		))
 	}
 
+	// Env is also special as it's a struct
+	{
+		ats := builder.GetFieldTags(c, "Env")
+		c.Env = StringsToEnv(builder.RequestSliceRaw(b, "Env", EnvToStrings(c.Env), []string{},
+			fmt.Sprintf("Enter the environment variables to pass to \"%s\". Use ${VAR} in a value to read VAR from the environment when starting (e.g. for secrets).\n", Deref(c.DockerHostname)),
+			[]string{
+				"Name %d: ",
+				"Value %d: ",
+			},
+			ats,
+		))
+	}
+	c.EnvFiles = builder.RequestSlice(b, c, "EnvFiles", []string{},
+		fmt.Sprintf("Enter the env files to pass to \"%s\" (relative to %s). Hit enter after each file.\n", Deref(c.DockerHostname), Deref(c.WorkDir)),
+		[]string{"Env file %d: "},
+	)
+
+	// Handlers are also special as they are a struct
+	{
+		ats := builder.GetFieldTags(c, "Handlers")
+		handlers, err := StringsToHandlers(builder.RequestSliceRaw(b, "Handlers", HandlersToStrings(c.Handlers), []string{},
+			fmt.Sprintf("Enter the routes to serve. Hit enter to just proxy https://%s/ to port %d\n", Deref(c.DockerHostname), Deref(c.Port)),
+			[]string{
+				"Tailnet port (443, 8443, and 10000 allow funnels) %d: ",
+				"Type (proxy, tcp, text, or file) %d: ",
+				"URL path prefix (- for tcp) %d: ",
+				"Local port (proxy or tcp), text, or host path (file) %d: ",
+			},
+			ats,
+		))
+		if err != nil {
+			return err
+		}
+		c.Handlers = handlers
+	}
+
+	// Services are also special as they are a struct. They're containers so systemd services can't have them.
+	if !c.Systemd() {
+		ats := builder.GetFieldTags(c, "Services")
+		services, err := StringsToServices(builder.RequestSliceRaw(b, "Services", ServicesToStrings(c.Services), []string{},
+			fmt.Sprintf("Enter any additional services (e.g. a database) to run alongside \"%s\"\n", Deref(c.DockerHostname)),
+			[]string{
+				"Service name %d: ",
+				"Docker image %d: ",
+				"Tailnet hostname (- to share the application's node) %d: ",
+				"TCP port to serve over HTTPS on its own node (0 for none) %d: ",
+			},
+			ats,
+		))
+		if err != nil {
+			return err
+		}
+		c.Services = requestServiceDetails(b, ats, c.Services, services)
+	}
+
 	changed := ""
 	if Deref(origConfiguration.DockerImage) != Deref(c.DockerImage) {
 		changed += fmt.Sprintf("Docker image: %s\n", *c.DockerImage)
@@ -237,26 +675,110 @@ func (c *Config) RequestMissingConfiguration() error {
 	if Deref(origConfiguration.GoCompilePath) != Deref(c.GoCompilePath) {
 		changed += fmt.Sprintf("Go main.go path %s\n", *c.GoCompilePath)
 	}
+	if Deref(origConfiguration.Backend) != Deref(c.Backend) {
+		changed += fmt.Sprintf("Backend: %s\n", *c.Backend)
+	}
+	if Deref(origConfiguration.Engine) != Deref(c.Engine) {
+		changed += fmt.Sprintf("Container engine: %s\n", *c.Engine)
+	}
+	if c.EngineRootless != nil && Deref(origConfiguration.EngineRootless) != *c.EngineRootless {
+		changed += fmt.Sprintf("Rootless: %t\n", *c.EngineRootless)
+	}
+	if Deref(origConfiguration.DockerHost) != Deref(c.DockerHost) {
+		changed += fmt.Sprintf("Docker host: %s\n", *c.DockerHost)
+	}
+	if Deref(origConfiguration.TargetPlatform) != Deref(c.TargetPlatform) {
+		changed += fmt.Sprintf("Target platform: %s\n", *c.TargetPlatform)
+	}
+	if Deref(origConfiguration.GoDockerBuild) != Deref(c.GoDockerBuild) {
+		changed += fmt.Sprintf("Build in Docker: %t\n", *c.GoDockerBuild)
+	}
+	if Deref(origConfiguration.GoRuntimeImage) != Deref(c.GoRuntimeImage) {
+		changed += fmt.Sprintf("Runtime image: %s\n", *c.GoRuntimeImage)
+	}
+	if Deref(origConfiguration.GoTsnet) != Deref(c.GoTsnet) {
+		changed += fmt.Sprintf("Join the tailnet with tsnet: %t\n", *c.GoTsnet)
+	}
 	if Deref(origConfiguration.Port) != Deref(c.Port) {
 		changed += fmt.Sprintf("Listening port %d\n", *c.Port)
 	}
 	if Deref(origConfiguration.Funnel) != Deref(c.Funnel) {
 		changed += fmt.Sprintf("Start a Tailscale funnel: %t\n", *c.Funnel)
 	}
+	if strings.Join(origConfiguration.TailscaleTags, "") != strings.Join(c.TailscaleTags, "") {
+		changed += fmt.Sprintf("Tailscale tags: %s\n", strings.Join(c.TailscaleTags, ", "))
+	}
+	if Deref(origConfiguration.TailscaleEphemeral) != Deref(c.TailscaleEphemeral) {
+		changed += fmt.Sprintf("Ephemeral Tailscale nodes: %t\n", *c.TailscaleEphemeral)
+	}
+	if Deref(origConfiguration.TailscaleAuthOnce) != Deref(c.TailscaleAuthOnce) {
+		changed += fmt.Sprintf("Only use TS_AUTHKEY to log in once: %t\n", *c.TailscaleAuthOnce)
+	}
+	if Deref(origConfiguration.TailscaleOAuth) != Deref(c.TailscaleOAuth) {
+		changed += fmt.Sprintf("TS_AUTHKEY is an OAuth client secret: %t\n", *c.TailscaleOAuth)
+	}
+	if Deref(origConfiguration.HealthCheck) != Deref(c.HealthCheck) {
+		changed += fmt.Sprintf("Health check: %s\n", *c.HealthCheck)
+	}
+	if Deref(origConfiguration.HealthCheckInterval) != Deref(c.HealthCheckInterval) {
+		changed += fmt.Sprintf("Health check interval: %s\n", *c.HealthCheckInterval)
+	}
+	if Deref(origConfiguration.HealthCheckRetries) != Deref(c.HealthCheckRetries) {
+		changed += fmt.Sprintf("Health check retries: %d\n", *c.HealthCheckRetries)
+	}
+	if Deref(origConfiguration.Registry) != Deref(c.Registry) {
+		changed += fmt.Sprintf("Registry: %s\n", *c.Registry)
+	}
+	if Deref(origConfiguration.RestartPolicy) != Deref(c.RestartPolicy) {
+		changed += fmt.Sprintf("Restart policy: %s\n", *c.RestartPolicy)
+	}
+	if Deref(origConfiguration.Resources).String() != Deref(c.Resources).String() {
+		changed += fmt.Sprintf("Resource limits: %s\n", Deref(c.Resources))
+	}
 	if fmt.Sprintf("%v", origConfiguration.DockerVolumes) != fmt.Sprintf("%v", c.DockerVolumes) {
 		for _, vol := range c.DockerVolumes {
 			changed += fmt.Sprintf("Volume: %s:%s\n", vol.DockerDir, vol.HostDir)
 		}
 	}
 
+	if fmt.Sprintf("%v", origConfiguration.Env) != fmt.Sprintf("%v", c.Env) {
+		for _, e := range c.Env {
+			changed += fmt.Sprintf("Environment variable: %s=%s\n", e.Name, e.Value)
+		}
+	}
+	if strings.Join(origConfiguration.EnvFiles, "") != strings.Join(c.EnvFiles, "") {
+		changed += fmt.Sprintf("Env files: %s\n", strings.Join(c.EnvFiles, ", "))
+	}
+	if fmt.Sprintf("%v", origConfiguration.Handlers) != fmt.Sprintf("%v", c.Handlers) {
+		for _, h := range c.Handlers {
+			changed += fmt.Sprintf("Route: %d %s %s -> %s\n", h.ListenPort, h.Type, h.Path, h.Target)
+		}
+	}
+	if fmt.Sprintf("%v", origConfiguration.Services) != fmt.Sprintf("%v", c.Services) {
+		for _, svc := range c.Services {
+			node := svc.Hostname
+			if node == "" {
+				node = Deref(c.DockerHostname)
+			}
+			changed += fmt.Sprintf("Service: %s (%s) on %s", svc.Name, svc.Image, node)
+			if svc.Funnel {
+				changed += " with a funnel"
+			}
+			for _, v := range svc.DockerVolumes {
+				changed += fmt.Sprintf(", volume %s:%s", v.HostDir, v.DockerDir)
+			}
+			for _, e := range svc.Env {
+				changed += fmt.Sprintf(", env %s", e.Name)
+			}
+			changed += "\n"
+		}
+	}
+
 	if changed != "" {
 		fmt.Printf("\n**********************************\n")
 		fmt.Println(changed)
 		fmt.Printf("**********************************\n\n")
-		fmt.Print("Are these changes correct? (y/n): ")
-		yOrN := ""
-		fmt.Scanf("%s", &yOrN)
-		if !strings.HasPrefix(strings.ToLower(yOrN), "y") {
+		if !b.Confirm("Are these changes correct? (y/n): ") {
 			return fmt.Errorf("Configuration is not correct. Cowardly quitting\n")
 		}
 	}
@@ -264,69 +786,136 @@ func (c *Config) RequestMissingConfiguration() error {
 	return nil
 }
 
-// Save saves the configuration to the .gots file
+// Save saves the configuration to the configuration file (see Path) keeping its format
 func (c *Config) Save() error {
-	jsonData, err := json.MarshalIndent(*c, "", "  ")
+	path, err := Path()
+	if err != nil {
+		return err
+	}
+	return c.SaveTo(path)
+}
+
+// SaveTo saves the configuration to path in the format matching its extension. If path already exists its comments
+// are kept.
+func (c *Config) SaveTo(path string) error {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("Unable to read %s %w\n", path, err)
+	}
+	data, err := c.Encode(FormatOf(path), existing)
 	if err != nil {
-		return fmt.Errorf("Unable to JSONify config\n")
+		return err
 	}
-	err = os.WriteFile(configPath, jsonData, 0644)
+	err = os.WriteFile(path, data, 0644)
 	if err != nil {
 		return fmt.Errorf("Unable to save config\n")
 	}
 
-	fmt.Printf("\nConfig saved to %s\n", configPath)
+	fmt.Printf("\nConfig saved to %s\n", path)
 	return nil
 }
 
+// templateFuncs are the extra functions available to the templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// indent indents each line of s by n spaces
+	"indent": func(n int, s string) string {
+		pad := strings.Repeat(" ", n)
+		return pad + strings.ReplaceAll(strings.TrimSuffix(s, "\n"), "\n", "\n"+pad)
+	},
+}
+
 type templates struct {
 	dstFileName     string
 	srcTemplateName string
 	srcTemplate     string
+	data            any // The data used to execute the template. If nil the configuration is used
 }
 
-// Generate creates all files needed to execute the executable in docker (Dockerfile, docker-compose.yaml, etc.)
+// executeTemplate parses the template and executes it with data writing the output to w
+func executeTemplate(w io.Writer, srcTemplateName string, srcTemplate string, data any) error {
+	templ, err := template.New(srcTemplateName).Funcs(templateFuncs).Parse(srcTemplate)
+	if err != nil {
+		return fmt.Errorf("Unable to parse %s\n", srcTemplateName)
+	}
+
+	err = templ.Execute(w, data)
+	if err != nil {
+		return fmt.Errorf("Unable to execute template %s %w\n", srcTemplateName, err)
+	}
+	return nil
+}
+
+// ServeConfigContent returns the node's serve config with $ escaped so it can be inlined in the docker-compose.yaml
+func (c Config) ServeConfigContent(n Node) (string, error) {
+	var buf bytes.Buffer
+	err := executeTemplate(&buf, "serve.config.template", serveConfigTemplate, n.serve)
+	if err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(buf.String(), "$", "$$"), nil
+}
+
+// Generate creates all files needed to execute the executable in docker (Dockerfile, docker-compose.yaml, etc.) in
+// dstDir
 func (c *Config) Generate(dstDir string) error {
-	for _, t := range []templates{
+	ts := []templates{
 		{
 			dstFileName:     "Dockerfile",
 			srcTemplateName: "Dockerfile.template",
 			srcTemplate:     dockerfileTemplate,
-		}, {
-			dstFileName:     "serve.config",
-			srcTemplateName: "serve.config.template",
-			srcTemplate:     serveConfigTemplate,
 		}, {
 			dstFileName:     "docker-compose.yaml",
 			srcTemplateName: "docker-compose.yaml.template",
 			srcTemplate:     dockerComposeTemplate,
-		}, {
-			dstFileName:     "gots-run",
-			srcTemplateName: "gots-run.template",
-			srcTemplate:     gotsRunTemplate,
 		},
-	} {
-		file, err := os.Create(t.dstFileName)
+	}
+	// The systemd units replace the container files. A serve.config is only needed by the launcher.
+	if c.Systemd() {
+		units, err := c.unitFiles(dstDir)
 		if err != nil {
-			return fmt.Errorf("Unable to open %s\n", t.dstFileName)
+			return err
 		}
-		defer file.Close()
-
-		templ, err := template.New(t.dstFileName).Parse(t.srcTemplate)
+		ts = units
+	}
+	// Each node that serves something gets its own serve.config
+	for _, node := range c.Nodes() {
+		if node.ServeConfig == "" || (c.Systemd() && !c.Tsnet()) {
+			continue
+		}
+		ts = append(ts, templates{
+			dstFileName:     node.ServeConfig,
+			srcTemplateName: "serve.config.template",
+			srcTemplate:     serveConfigTemplate,
+			data:            node.serve,
+		})
+	}
+	// The launcher is built from source alongside the executable
+	if c.Tsnet() {
+		_, err := launcher.Write(dstDir)
 		if err != nil {
-			return fmt.Errorf("Unable to parse %s\n", t.srcTemplateName)
+			return err
 		}
+	}
 
-		err = templ.Execute(file, *c)
+	for _, t := range ts {
+		file, err := os.Create(filepath.Join(dstDir, t.dstFileName))
 		if err != nil {
-			return fmt.Errorf("Unable to execute template %s %w\n", t.srcTemplateName, err)
+			return fmt.Errorf("Unable to open %s\n", t.dstFileName)
 		}
+		defer file.Close()
 
-		if t.dstFileName == "gots-run" {
-			os.Chmod(t.dstFileName, 0755)
-			if err != nil {
-				return fmt.Errorf("Unable chmod %s\n", t.dstFileName)
-			}
+		var data any = *c
+		if t.data != nil {
+			data = t.data
+		}
+		err = executeTemplate(file, t.srcTemplateName, t.srcTemplate, data)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -1,12 +1,22 @@
 package config_test
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
+func Ptr[T any](t T) *T {
+	return &t
+}
+
 func TestVolumesToStringsToVolumes(t *testing.T) {
 	expected := []config.Volume{
 		{
@@ -52,3 +62,180 @@ func TestFilterSlice(t *testing.T) {
 
 	require.Equal(t, []string{"A", "C"}, resp)
 }
+
+func TestAnswers(t *testing.T) {
+	cfg := config.Config{
+		Type:          "go",
+		ExecName:      Ptr("app"),
+		Port:          Ptr(8080),
+		ExecArgs:      []string{"-v"},
+		DockerVolumes: []config.Volume{{DockerDir: "/data", HostDir: "/srv/data"}},
+	}
+
+	require.Equal(t, map[string]any{
+		"ExecName":      "app",
+		"Port":          8080,
+		"ExecArgs":      []string{"-v"},
+		"DockerVolumes": []string{"/data", "/srv/data"},
+	}, cfg.Answers())
+}
+
+func TestRequestMissingConfigurationFromAnswers(t *testing.T) {
+	answers := config.Config{
+		ExecName:      Ptr("app"),
+		GoCompilePath: Ptr("./cmd/app"),
+		WorkDir:       Ptr("/src"),
+		Port:          Ptr(8080),
+		Funnel:        Ptr(true),
+		ExecArgs:      []string{"-v"},
+		DockerVolumes: []config.Volume{{DockerDir: "/data", HostDir: "/srv/data"}},
+	}
+	b := builder.New(strings.NewReader(""), builder.AppTypeGo).AssumeYes()
+	for name, value := range answers.Answers() {
+		b.Answer(name, value)
+	}
+
+	cfg := config.Config{Type: builder.AppTypeGo}
+	err := cfg.RequestMissingConfiguration(b)
+	require.NoError(t, err)
+
+	require.Equal(t, "app", *cfg.ExecName)
+	require.Equal(t, "app", *cfg.DockerHostname)
+	require.Equal(t, "app", *cfg.DockerImage)
+	require.Equal(t, "./cmd/app", *cfg.GoCompilePath)
+	require.Equal(t, "/src", *cfg.WorkDir)
+	require.Equal(t, 8080, *cfg.Port)
+	require.True(t, *cfg.Funnel)
+	require.Equal(t, []string{"-v"}, cfg.ExecArgs)
+	require.Equal(t, answers.DockerVolumes, cfg.DockerVolumes)
+	require.True(t, cfg.ValidateComplete())
+}
+
+func TestRequestMissingConfigurationEdit(t *testing.T) {
+	answers := config.Config{
+		ExecName:      Ptr("app"),
+		GoCompilePath: Ptr("./cmd/app"),
+		WorkDir:       Ptr("/src"),
+		Port:          Ptr(8080),
+		Funnel:        Ptr(false),
+	}
+	newConfig := func() *config.Config {
+		b := builder.New(terminal(""), builder.AppTypeGo).AssumeYes()
+		for name, value := range answers.Answers() {
+			b.Answer(name, value)
+		}
+		cfg := &config.Config{Type: builder.AppTypeGo}
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		return cfg
+	}
+
+	t.Run("reset computes the value again", func(t *testing.T) {
+		cfg := newConfig()
+		require.NoError(t, cfg.RequestMissingConfiguration(builder.New(terminal(""), builder.AppTypeGo).AssumeYes().Reset("WorkDir")))
+		wd, err := os.Getwd()
+		require.NoError(t, err)
+		require.Equal(t, wd, *cfg.WorkDir)
+	})
+
+	t.Run("the hostname and image of a go app aren't requested", func(t *testing.T) {
+		cfg := newConfig()
+		// If the hostname were requested it would be docker
+		require.NoError(t, cfg.RequestMissingConfiguration(builder.New(terminal("server\ndocker\n"), builder.AppTypeGo).AssumeYes().Edit()))
+		require.Equal(t, "server", *cfg.ExecName)
+		require.Equal(t, "server", *cfg.DockerHostname)
+		require.Equal(t, "server", *cfg.DockerImage)
+		require.Equal(t, config.BackendDocker, *cfg.Backend)
+	})
+}
+
+func TestLoadAnswers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "answers.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"ExecName": "app", "Port": 8080}`), 0644))
+
+	answers, err := config.LoadAnswers(path)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"ExecName": "app", "Port": 8080}, answers.Answers())
+
+	_, err = config.LoadAnswers(filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestGenerate(t *testing.T) {
+	cfg := imageConfig()
+	cfg.DockerImage = Ptr("nginx")
+	cfg.DockerHostname = Ptr("web")
+	cfg.DockerVolumes = []config.Volume{{DockerDir: "/data", HostDir: "/srv/data"}}
+	cfg.Services = []config.Service{
+		{Name: "db", Image: "postgres:17"},
+		{Name: "admin", Image: "adminer", Hostname: "web-admin", Port: 8080, Funnel: true},
+	}
+	dir := generate(t, cfg)
+
+	services := parseCompose(t, []byte(readGenerated(t, dir, "docker-compose.yaml")))
+	require.ElementsMatch(t, []string{"ts-web", "ts-web-admin", "web", "db", "admin"}, keys(services))
+	require.Equal(t, "service:ts-web", services["web"]["network_mode"])
+	require.Equal(t, []any{"/srv/data:/data"}, services["web"]["volumes"])
+	require.Equal(t, "service:ts-web", services["db"]["network_mode"])
+	require.Equal(t, "service:ts-web-admin", services["admin"]["network_mode"])
+	require.Equal(t, []any{
+		"/src/.tailscale-web-admin:/var/lib/tailscale",
+		"${PWD}/serve.web-admin.config:/config/serve.config",
+	}, services["ts-web-admin"]["volumes"])
+
+	serve := readGenerated(t, dir, "serve.web-admin.config")
+	require.Contains(t, serve, `"Proxy": "http://127.0.0.1:8080"`)
+	require.Contains(t, serve, `"${TS_CERT_DOMAIN}:443": true`)
+}
+
+func TestGoEnv(t *testing.T) {
+	require.Nil(t, config.Config{}.GoEnv())
+	require.Equal(t, []string{"GOOS=linux", "GOARCH=arm64"}, config.Config{TargetPlatform: Ptr("linux/arm64")}.GoEnv())
+	require.Equal(t, []string{"GOOS=linux", "GOARCH=arm", "GOARM=7"}, config.Config{TargetPlatform: Ptr("linux/arm/v7")}.GoEnv())
+}
+
+func TestGeneratePodman(t *testing.T) {
+	cfg := imageConfig()
+	cfg.Engine = Ptr("podman")
+
+	services := generateCompose(t, cfg)
+	require.Contains(t, services["ts-app"]["environment"], "TS_USERSPACE=true")
+	require.NotContains(t, services["ts-app"], "devices")
+	require.NotContains(t, services["ts-app"], "cap_add")
+
+	// Rootful podman can use /dev/net/tun
+	cfg.EngineRootless = Ptr(false)
+	services = generateCompose(t, cfg)
+	require.NotContains(t, services["ts-app"]["environment"], "TS_USERSPACE=true")
+	require.Contains(t, services["ts-app"], "devices")
+}
+
+func TestContainerEngineDetected(t *testing.T) {
+	// Without an Engine the installed one is used
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "podman"), []byte("#!/bin/sh\n"), 0755))
+	t.Setenv("PATH", dir)
+	require.Equal(t, "podman", config.Config{}.ContainerEngine().Name())
+	require.Equal(t, "docker", config.Config{Engine: Ptr("docker")}.ContainerEngine().Name())
+}
+
+func TestGenerateRemote(t *testing.T) {
+	cfg := imageConfig()
+	cfg.DockerHost = Ptr("ssh://me@server")
+	data := []byte(readGenerated(t, generate(t, cfg), "docker-compose.yaml"))
+
+	compose := struct {
+		Services map[string]map[string]any
+		Volumes  map[string]any
+		Configs  map[string]struct{ Content string }
+	}{}
+	require.NoError(t, yaml.Unmarshal(data, &compose), string(data))
+	require.Equal(t, []any{"ts-app-state:/var/lib/tailscale"}, compose.Services["ts-app"]["volumes"])
+	require.Equal(t, []any{map[string]any{"source": "serve-app", "target": "/config/serve.config"}}, compose.Services["ts-app"]["configs"])
+	require.Contains(t, compose.Volumes, "ts-app-state")
+
+	// The inlined serve config is escaped so docker compose doesn't interpolate ${TS_CERT_DOMAIN}
+	serve := map[string]any{}
+	content := strings.ReplaceAll(compose.Configs["serve-app"].Content, "$$", "$")
+	require.NoError(t, json.Unmarshal([]byte(content), &serve))
+	require.Contains(t, serve["Web"], "${TS_CERT_DOMAIN}:443")
+}
@@ -0,0 +1,37 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerfile(t *testing.T) {
+	cfg := goConfig()
+	cfg.ExecArgs = []string{"-port", "80"}
+	cfg.WorkDir = Ptr(goModDir(t))
+
+	t.Run("host build", func(t *testing.T) {
+		dockerfile := readGenerated(t, generate(t, cfg), "Dockerfile")
+		require.Contains(t, dockerfile, "FROM ubuntu:latest\n\nRUN apt-get update\n")
+		require.Contains(t, dockerfile, `CMD "/bin/app" "-port" "80"`)
+	})
+
+	t.Run("docker build", func(t *testing.T) {
+		cfg.GoDockerBuild = Ptr(true)
+		dockerfile := readGenerated(t, generate(t, cfg), "Dockerfile")
+		require.Contains(t, dockerfile, "FROM --platform=$BUILDPLATFORM golang:1.25.1 AS build\n")
+		require.Contains(t, dockerfile, "RUN CGO_ENABLED=0 GOOS=$TARGETOS GOARCH=$TARGETARCH go build -trimpath -o /out/app ./cmd/app\n")
+		require.Contains(t, dockerfile, "FROM gcr.io/distroless/static-debian12\n")
+		require.NotContains(t, dockerfile, "ca-certificates")
+		require.Contains(t, dockerfile, `CMD ["/bin/app","-port","80"]`)
+	})
+
+	t.Run("docker build on scratch", func(t *testing.T) {
+		cfg.GoDockerBuild = Ptr(true)
+		cfg.GoRuntimeImage = Ptr("scratch")
+		dockerfile := readGenerated(t, generate(t, cfg), "Dockerfile")
+		require.Contains(t, dockerfile, "FROM scratch\n")
+		require.Contains(t, dockerfile, "COPY --from=build /etc/ssl/certs/ca-certificates.crt /etc/ssl/certs/\n")
+	})
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// EnvVar is an environment variable passed to a container. The Value may reference host environment variables as
+// ${VAR} which are resolved when the application is started so secrets don't need to be stored in the .gots.
+type EnvVar struct {
+	Name  string
+	Value string
+}
+
+// EnvToStrings flattens environment variables into the strings requested by the configuration wizard
+func EnvToStrings(es []EnvVar) []string {
+	if es == nil {
+		return nil
+	}
+	ret := []string{}
+	for _, e := range es {
+		ret = append(ret, e.Name, e.Value)
+	}
+	return ret
+}
+
+// StringsToEnv converts the strings requested by the configuration wizard into environment variables
+func StringsToEnv(strs []string) []EnvVar {
+	if strs == nil {
+		return nil
+	}
+	ret := []EnvVar{}
+	for i := 0; i+1 < len(strs); i += 2 {
+		ret = append(ret, EnvVar{Name: strs[i], Value: strs[i+1]})
+	}
+	return ret
+}
+
+// envReference matches ${VAR} references. References with a default (${VAR:-default} or ${VAR-default}) are
+// captured so they can be ignored.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:?-[^}]*)?\}`)
+
+// EnvReferences returns the names of the host environment variables that must be set to resolve the ${VAR}
+// references in the application's and services' environment variables
+func (c Config) EnvReferences() []string {
+	vars := []EnvVar{}
+	vars = append(vars, c.Env...)
+	for _, svc := range c.Services {
+		vars = append(vars, svc.Env...)
+	}
+
+	names := []string{}
+	seen := map[string]bool{}
+	for _, v := range vars {
+		for _, match := range envReference.FindAllStringSubmatch(v.Value, -1) {
+			if match[2] != "" || seen[match[1]] {
+				continue
+			}
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// EnvFilePath returns the absolute path to an env file. Relative paths are relative to the WorkDir.
+func (c Config) EnvFilePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(Deref(c.WorkDir), path)
+}
+
+// ExpandEnv resolves the ${VAR} references in value from the host environment the way docker compose does. A
+// reference with a default (${VAR:-default} or ${VAR-default}) uses it if VAR is empty or unset respectively, and $$
+// is a literal $.
+func ExpandEnv(value string) string {
+	parts := strings.Split(value, "$$")
+	for i, part := range parts {
+		parts[i] = envReference.ReplaceAllStringFunc(part, func(ref string) string {
+			match := envReference.FindStringSubmatch(ref)
+			v, ok := os.LookupEnv(match[1])
+			switch {
+			case strings.HasPrefix(match[2], ":-") && v == "":
+				return match[2][2:]
+			case strings.HasPrefix(match[2], "-") && !ok:
+				return match[2][1:]
+			}
+			return v
+		})
+	}
+	return strings.Join(parts, "$")
+}
+
+// ResolvedEnv returns the application's environment variables as NAME=value with the ${VAR} references resolved
+func (c Config) ResolvedEnv() []string {
+	env := []string{}
+	for _, e := range c.Env {
+		env = append(env, e.Name+"="+ExpandEnv(e.Value))
+	}
+	return env
+}
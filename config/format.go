@@ -0,0 +1,470 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the file format of a configuration
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+)
+
+// configPaths are the configuration files that are looked for (in order)
+var configPaths = []string{configPath, configPath + ".yaml", configPath + ".yml", configPath + ".toml"}
+
+// Path returns the path of the configuration file. If there isn't one the JSON path (./.gots) is returned. It's an
+// error for there to be more than one as it isn't clear which one is meant.
+func Path() (string, error) {
+	found := []string{}
+	for _, path := range configPaths {
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return configPath, nil
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("Found %s, remove all but one of them\n", strings.Join(found, ", "))
+}
+
+// PathFor returns the path of the configuration file in the given format
+func PathFor(f Format) string {
+	if f == FormatJSON {
+		return configPath
+	}
+	return configPath + "." + string(f)
+}
+
+// ParseFormat converts the name of a format (json, yaml, yml, or toml) to a Format
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("Unknown configuration format %s (json, yaml, or toml)\n", name)
+}
+
+// FormatOf returns the format of a configuration file based on its extension. Anything that isn't YAML or TOML is
+// JSON.
+func FormatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	}
+	return FormatJSON
+}
+
+// ParseAs parses the contents of a configuration file in the given format. Errors include the position of the
+// problem when the parser provides it.
+func ParseAs(f Format, data []byte) (*Config, error) {
+	var values any
+	switch f {
+	case FormatYAML:
+		err := yaml.Unmarshal(data, &values)
+		if err != nil {
+			// yaml errors already include the line
+			return nil, fmt.Errorf("%w\n", err)
+		}
+	case FormatTOML:
+		tomlValues := map[string]any{}
+		err := toml.Unmarshal(data, &tomlValues)
+		if err != nil {
+			var decodeErr *toml.DecodeError
+			if errors.As(err, &decodeErr) {
+				line, column := decodeErr.Position()
+				return nil, fmt.Errorf("line %d column %d: %w\n", line, column, err)
+			}
+			return nil, fmt.Errorf("%w\n", err)
+		}
+		values = tomlValues
+	default:
+		return Parse(data)
+	}
+
+	// YAML and TOML are converted to JSON so the field names are the same in every format
+	if values == nil {
+		return &Config{}, nil
+	}
+	jsonData, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("%w\n", err)
+	}
+	cfg := Config{}
+	err = json.Unmarshal(jsonData, &cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%w\n", err)
+	}
+	return &cfg, nil
+}
+
+// Encode encodes the configuration in the given format. existing is the current contents of the file (or nil), its
+// comments are kept.
+func (c *Config) Encode(f Format, existing []byte) ([]byte, error) {
+	jsonData, err := json.MarshalIndent(*c, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to JSONify config\n")
+	}
+	if f == FormatJSON {
+		return jsonData, nil
+	}
+
+	// The JSON is converted into a yaml.Node as it keeps the order of the fields
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+	node, err := jsonToNode(dec)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to convert config %w\n", err)
+	}
+
+	if f == FormatTOML {
+		return encodeTOML(node, parseTOMLComments(existing))
+	}
+
+	doc := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{node}}
+	old := yaml.Node{}
+	if len(existing) > 0 && yaml.Unmarshal(existing, &old) == nil {
+		copyComments(&old, doc)
+	}
+	buf := bytes.Buffer{}
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	err = enc.Encode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to YAMLify config %w\n", err)
+	}
+	err = enc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to YAMLify config %w\n", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// jsonToNode reads the next JSON value from dec. Null object fields are dropped as YAML and TOML treat missing
+// fields the same way.
+func jsonToNode(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		if t == '{' {
+			node = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		}
+		for dec.More() {
+			var key *yaml.Node
+			if node.Kind == yaml.MappingNode {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				key = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: keyTok.(string)}
+			}
+			value, err := jsonToNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				node.Content = append(node.Content, value)
+			} else if value.Tag != "!!null" {
+				node.Content = append(node.Content, key, value)
+			}
+		}
+		// The closing delimiter
+		_, err := dec.Token()
+		return node, err
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(t.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(t)}, nil
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+}
+
+// copyComments copies the comments from the old node to the matching parts of the new node. Mapping values are matched
+// by key and sequence items by index.
+func copyComments(old *yaml.Node, new *yaml.Node) {
+	if old.Kind == yaml.DocumentNode && new.Kind == yaml.DocumentNode && len(old.Content) > 0 && len(new.Content) > 0 {
+		copyComments(old.Content[0], new.Content[0])
+	}
+	if new.HeadComment == "" {
+		new.HeadComment = old.HeadComment
+	}
+	if new.LineComment == "" {
+		new.LineComment = old.LineComment
+	}
+	if new.FootComment == "" {
+		new.FootComment = old.FootComment
+	}
+
+	switch {
+	case old.Kind == yaml.MappingNode && new.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(new.Content); i += 2 {
+			for j := 0; j+1 < len(old.Content); j += 2 {
+				if old.Content[j].Value == new.Content[i].Value {
+					copyComments(old.Content[j], new.Content[i])
+					copyComments(old.Content[j+1], new.Content[i+1])
+					break
+				}
+			}
+		}
+	case old.Kind == yaml.SequenceNode && new.Kind == yaml.SequenceNode:
+		for i := 0; i < len(old.Content) && i < len(new.Content); i++ {
+			copyComments(old.Content[i], new.Content[i])
+		}
+	}
+}
+
+// encodeTOML encodes a mapping node as TOML keeping the comments
+func encodeTOML(node *yaml.Node, comments tomlComments) ([]byte, error) {
+	value, err := tomlValue(node)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to TOMLify config %w\n", err)
+	}
+	data, err := toml.Marshal(value.Interface())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to TOMLify config %w\n", err)
+	}
+
+	buf := bytes.Buffer{}
+	for _, line := range scanTOML(bytes.TrimSuffix(data, []byte("\n"))) {
+		text := line.text
+		if line.path != "" {
+			head, comment := comments.find(line.path)
+			for _, c := range head {
+				buf.WriteString(c + "\n")
+			}
+			if comment != "" {
+				text += " " + comment
+			}
+		}
+		buf.WriteString(text + "\n")
+	}
+	for _, comment := range comments.foot {
+		buf.WriteString(comment + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// tomlValue converts a node to a value that go-toml encodes in the same order. go-toml sorts the keys of maps so
+// mappings are converted to structs, whose fields are encoded in order.
+func tomlValue(node *yaml.Node) (reflect.Value, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		fields := []reflect.StructField{}
+		values := []reflect.Value{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := tomlValue(node.Content[i+1])
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			fields = append(fields, reflect.StructField{
+				Name: fmt.Sprintf("F%d", len(fields)),
+				Type: value.Type(),
+				Tag:  reflect.StructTag("toml:" + strconv.Quote(node.Content[i].Value)),
+			})
+			values = append(values, value)
+		}
+		table := reflect.New(reflect.StructOf(fields)).Elem()
+		for i, value := range values {
+			table.Field(i).Set(value)
+		}
+		return table, nil
+	case yaml.SequenceNode:
+		items := []any{}
+		for _, item := range node.Content {
+			value, err := tomlValue(item)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			items = append(items, value.Interface())
+		}
+		return reflect.ValueOf(items), nil
+	}
+	switch node.Tag {
+	case "!!int":
+		n, err := strconv.ParseInt(node.Value, 10, 64)
+		return reflect.ValueOf(n), err
+	case "!!float":
+		f, err := strconv.ParseFloat(node.Value, 64)
+		return reflect.ValueOf(f), err
+	case "!!bool":
+		return reflect.ValueOf(node.Value == "true"), nil
+	}
+	return reflect.ValueOf(node.Value), nil
+}
+
+// tomlComments are the comments in a TOML configuration by the path of the key or table they belong to (e.g.
+// Services.0.Env.1.Name). Only the comments outside of values are kept.
+type tomlComments struct {
+	// head are the comment lines before each key or table header
+	head map[string][]string
+	// line is the comment at the end of the line of each key or table header
+	line map[string]string
+	// foot are the comment lines after the last key
+	foot []string
+}
+
+// parseTOMLComments finds the comments in data
+func parseTOMLComments(data []byte) tomlComments {
+	comments := tomlComments{head: map[string][]string{}, line: map[string]string{}}
+	pending := []string{}
+	for _, line := range scanTOML(data) {
+		if line.path != "" {
+			comments.head[line.path] = pending
+			pending = []string{}
+			if line.comment != "" {
+				comments.line[line.path] = line.comment
+			}
+		} else if line.comment != "" && strings.TrimSpace(line.code) == "" {
+			pending = append(pending, line.comment)
+		}
+	}
+	comments.foot = pending
+	return comments
+}
+
+// find returns the comments of path. Arrays of tables may have been written as inline tables, so the comments of the
+// array's key are used for its first table.
+func (c tomlComments) find(path string) ([]string, string) {
+	if _, ok := c.head[path]; !ok && strings.HasSuffix(path, ".0") {
+		path = strings.TrimSuffix(path, ".0")
+	}
+	return c.head[path], c.line[path]
+}
+
+// tomlLine is a line of a TOML document
+type tomlLine struct {
+	text string
+	// code is the line without its comment
+	code string
+	// comment is the comment at the end of the line. Comments inside of values are ignored.
+	comment string
+	// path is the path of the key or table header on the line or "" if there isn't one
+	path string
+}
+
+var (
+	// tomlKey matches a key outside of a value
+	tomlKey = regexp.MustCompile(`^\s*("[^"]*"|'[^']*'|[A-Za-z0-9_-]+)\s*=`)
+	// tomlTable matches a table header
+	tomlTable = regexp.MustCompile(`^\s*\[\s*([^\[\]]+?)\s*\]\s*$`)
+	// tomlArrayTable matches an array of tables header
+	tomlArrayTable = regexp.MustCompile(`^\s*\[\[\s*([^\[\]]+?)\s*\]\]\s*$`)
+)
+
+// scanTOML splits data into lines and finds the path of the key or table header on each of them
+func scanTOML(data []byte) []tomlLine {
+	lines := []tomlLine{}
+	table := ""
+	// arrays are the number of tables in each array of tables
+	arrays := map[string]int{}
+	depth := 0
+	for _, text := range strings.Split(string(data), "\n") {
+		code, comment, delta := splitTOMLLine(text)
+		line := tomlLine{text: text, code: code}
+		if depth == 0 {
+			line.comment = comment
+			if match := tomlArrayTable.FindStringSubmatch(code); match != nil {
+				table = tomlTablePath(match[1], arrays, true)
+				line.path = table
+			} else if match := tomlTable.FindStringSubmatch(code); match != nil {
+				table = tomlTablePath(match[1], arrays, false)
+				line.path = table
+			} else if match := tomlKey.FindStringSubmatch(code); match != nil {
+				line.path = joinTOMLPath(table, strings.Trim(match[1], `"'`))
+			}
+		}
+		lines = append(lines, line)
+		depth += delta
+	}
+	return lines
+}
+
+// tomlTablePath returns the path of a table header. The tables in an array of tables are numbered so a header in an
+// array of tables (e.g. [[Services.Env]]) belongs to the last table of the array.
+func tomlTablePath(name string, arrays map[string]int, array bool) string {
+	path := ""
+	keys := strings.Split(name, ".")
+	for i, key := range keys {
+		path = joinTOMLPath(path, strings.Trim(strings.TrimSpace(key), `"'`))
+		count, ok := arrays[path]
+		if array && i == len(keys)-1 {
+			arrays[path] = count + 1
+			return joinTOMLPath(path, strconv.Itoa(count))
+		}
+		if ok {
+			path = joinTOMLPath(path, strconv.Itoa(count-1))
+		}
+	}
+	return path
+}
+
+// joinTOMLPath appends key to path
+func joinTOMLPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// splitTOMLLine splits a line into its code and comment and returns how much it changes the nesting of arrays and
+// inline tables
+func splitTOMLLine(line string) (string, string, int) {
+	depth := 0
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if escaped {
+				escaped = false
+			} else if r == '\\' && quote == '"' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '{':
+			depth++
+		case r == ']' || r == '}':
+			depth--
+		case r == '#':
+			return line[:i], strings.TrimSpace(line[i:]), depth
+		}
+	}
+	return line, "", depth
+}
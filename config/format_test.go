@@ -0,0 +1,182 @@
+package config_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, format := range []config.Format{config.FormatJSON, config.FormatYAML, config.FormatTOML} {
+		t.Run(string(format), func(t *testing.T) {
+			cfg := formatConfig()
+
+			data, err := cfg.Encode(format, nil)
+			require.NoError(t, err)
+			parsed, err := config.ParseAs(format, data)
+			require.NoError(t, err)
+
+			require.Equal(t, cfg, *parsed)
+		})
+	}
+}
+
+func TestEncodeYAMLKeepsComments(t *testing.T) {
+	existing := []byte(`# The web server
+Version: 1
+Type: dockerimage
+DockerImage: nginx # pinned by ops
+# Only on the tailnet
+Port: 80
+DockerVolumes:
+  # Site content
+  - DockerDir: /data
+    HostDir: /srv/data
+`)
+	cfg, err := config.ParseAs(config.FormatYAML, existing)
+	require.NoError(t, err)
+	cfg.Port = Ptr(8080)
+
+	data, err := cfg.Encode(config.FormatYAML, existing)
+	require.NoError(t, err)
+
+	require.Equal(t, `# The web server
+Version: 1
+Type: dockerimage
+DockerImage: nginx # pinned by ops
+# Only on the tailnet
+Port: 8080
+DockerVolumes:
+  # Site content
+  - DockerDir: /data
+    HostDir: /srv/data
+`, string(data))
+}
+
+func TestEncodeTOMLKeepsComments(t *testing.T) {
+	existing := []byte(`# The web server
+Version = 1
+Type = "dockerimage"
+DockerImage = "nginx" # pinned by ops
+
+# Only on the tailnet
+Port = 80
+# Site content
+DockerVolumes = [
+  { DockerDir = "/data", HostDir = "/srv/data" },
+]
+
+[[Services]]
+Name = "db"
+Image = "postgres:17"
+
+# The cache
+[[Services]]
+Name = "cache"
+# Pinned
+Image = "redis:7"
+
+[[Services.Env]]
+# Required
+Name = "REDIS_ARGS" # as of 7.4
+Value = "--save 60 1"
+
+# Limits
+[Resources]
+# Plenty for nginx
+Memory = "512m" # raised after the outage
+# The end
+`)
+	cfg, err := config.ParseAs(config.FormatTOML, existing)
+	require.NoError(t, err)
+	cfg.Port = Ptr(8080)
+
+	data, err := cfg.Encode(config.FormatTOML, existing)
+	require.NoError(t, err)
+
+	require.Equal(t, `# The web server
+Version = 1
+Type = 'dockerimage'
+DockerImage = 'nginx' # pinned by ops
+# Only on the tailnet
+Port = 8080
+
+# Site content
+[[DockerVolumes]]
+DockerDir = '/data'
+HostDir = '/srv/data'
+
+[[Services]]
+Name = 'db'
+Image = 'postgres:17'
+
+# The cache
+[[Services]]
+Name = 'cache'
+# Pinned
+Image = 'redis:7'
+
+[[Services.Env]]
+# Required
+Name = 'REDIS_ARGS' # as of 7.4
+Value = '--save 60 1'
+
+# Limits
+[Resources]
+# Plenty for nginx
+Memory = '512m' # raised after the outage
+# The end
+`, string(data))
+
+	// The encoded file is parsed the same way so its comments are kept when it's rewritten
+	again, err := cfg.Encode(config.FormatTOML, data)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(again))
+}
+
+func TestParseAsMalformed(t *testing.T) {
+	_, err := config.ParseAs(config.FormatYAML, []byte("Version: 1\nPort: 80: 443\n"))
+	require.ErrorContains(t, err, "line 2")
+
+	_, err = config.ParseAs(config.FormatTOML, []byte("Version = 1\nPort = = 80\n"))
+	require.ErrorContains(t, err, "line 2 column")
+
+	_, err = config.ParseAs(config.FormatYAML, []byte("Port: eighty\n"))
+	require.Error(t, err)
+}
+
+func TestFormatOf(t *testing.T) {
+	require.Equal(t, config.FormatJSON, config.FormatOf("./.gots"))
+	require.Equal(t, config.FormatYAML, config.FormatOf("./.gots.yaml"))
+	require.Equal(t, config.FormatYAML, config.FormatOf("answers.yml"))
+	require.Equal(t, config.FormatTOML, config.FormatOf("./.gots.toml"))
+
+	format, err := config.ParseFormat("YML")
+	require.NoError(t, err)
+	require.Equal(t, config.FormatYAML, format)
+	require.Equal(t, "./.gots.yaml", config.PathFor(format))
+	_, err = config.ParseFormat("ini")
+	require.Error(t, err)
+}
+
+func TestPath(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	path, err := config.Path()
+	require.NoError(t, err)
+	require.Equal(t, "./.gots", path)
+
+	require.NoError(t, os.WriteFile(".gots.toml", []byte("Version = 1\n"), 0644))
+	path, err = config.Path()
+	require.NoError(t, err)
+	require.Equal(t, "./.gots.toml", path)
+
+	// It isn't clear which file to use
+	require.NoError(t, os.WriteFile(".gots", []byte("{}"), 0644))
+	_, err = config.Path()
+	require.ErrorContains(t, err, "./.gots, ./.gots.toml")
+	_, err = config.Load()
+	require.Error(t, err)
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/launcher"
+)
+
+const (
+	// HealthCheckTCP probes the application's Port with a TCP connection instead of an HTTP request
+	HealthCheckTCP = "tcp"
+	// DefaultHealthCheckInterval is the default time between health checks
+	DefaultHealthCheckInterval = "10s"
+	// DefaultHealthCheckRetries is the default number of failed health checks before the application is unhealthy
+	DefaultHealthCheckRetries = 3
+	// DefaultHealthCheckTimeout is the longest a health check can take before it fails
+	DefaultHealthCheckTimeout = 5 * time.Second
+)
+
+// HealthCheckSafe returns the application's health check, an HTTP path or HealthCheckTCP. Empty if there isn't one.
+func (c Config) HealthCheckSafe() string {
+	return Deref(c.HealthCheck)
+}
+
+// HealthCheckURL returns the URL that is probed to check the application's health (http://... or tcp://...)
+func (c Config) HealthCheckURL() string {
+	if c.HealthCheckSafe() == HealthCheckTCP {
+		return fmt.Sprintf("tcp://127.0.0.1:%d", Deref(c.Port))
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d%s", Deref(c.Port), c.HealthCheckSafe())
+}
+
+// HealthCheckIntervalSafe returns the time between health checks
+func (c Config) HealthCheckIntervalSafe() time.Duration {
+	interval, err := time.ParseDuration(Deref(c.HealthCheckInterval))
+	if err != nil || interval <= 0 {
+		interval, _ = time.ParseDuration(DefaultHealthCheckInterval)
+	}
+	return interval
+}
+
+// HealthCheckTimeoutSafe returns how long a health check can take before it fails. It's less than the interval so a
+// hung check fails before the next one is due.
+func (c Config) HealthCheckTimeoutSafe() time.Duration {
+	return min(DefaultHealthCheckTimeout, c.HealthCheckIntervalSafe()/2)
+}
+
+// HealthCheckRetriesSafe returns the number of failed health checks before the application is unhealthy
+func (c Config) HealthCheckRetriesSafe() int {
+	if Deref(c.HealthCheckRetries) < 1 {
+		return DefaultHealthCheckRetries
+	}
+	return *c.HealthCheckRetries
+}
+
+// HealthCheckTest returns the compose healthcheck test. It's run in the container that owns the application's
+// network: the tailscale sidecar (whose image has wget and nc) or the application's own container in tsnet mode
+// where the launcher does the probing. Application images often don't have a shell or any tools.
+func (c Config) HealthCheckTest() []string {
+	if c.Tsnet() {
+		return []string{"CMD", "/bin/" + launcher.Name, "-probe", c.HealthCheckURL()}
+	}
+	if c.HealthCheckSafe() == HealthCheckTCP {
+		return []string{"CMD", "nc", "-z", "127.0.0.1", fmt.Sprint(Deref(c.Port))}
+	}
+	return []string{"CMD", "wget", "-q", "-O", "/dev/null", c.HealthCheckURL()}
+}
+
+// validateHealthCheck checks the health check fields
+func (c Config) validateHealthCheck(add func(string, string, ...any)) {
+	if check := c.HealthCheckSafe(); check != "" && check != HealthCheckTCP && !strings.HasPrefix(check, "/") {
+		add("HealthCheck", "%q must be an HTTP path (starting with /) or %s", check, HealthCheckTCP)
+	}
+	if c.HealthCheckInterval != nil {
+		if interval, err := time.ParseDuration(*c.HealthCheckInterval); err != nil || interval <= 0 {
+			add("HealthCheckInterval", "%q isn't a duration (e.g. 10s)", *c.HealthCheckInterval)
+		}
+	}
+	if c.HealthCheckRetries != nil && *c.HealthCheckRetries < 1 {
+		add("HealthCheckRetries", "must be at least 1")
+	}
+}
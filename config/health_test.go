@@ -0,0 +1,63 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthCheckTest(t *testing.T) {
+	cfg := config.Config{Port: Ptr(8080), HealthCheck: Ptr("/healthz")}
+	require.Equal(t, []string{"CMD", "wget", "-q", "-O", "/dev/null", "http://127.0.0.1:8080/healthz"}, cfg.HealthCheckTest())
+
+	cfg.HealthCheck = Ptr(config.HealthCheckTCP)
+	require.Equal(t, []string{"CMD", "nc", "-z", "127.0.0.1", "8080"}, cfg.HealthCheckTest())
+
+	// The launcher probes the application in tsnet mode
+	cfg.Type = builder.AppTypeGo
+	cfg.GoTsnet = Ptr(true)
+	require.Equal(t, []string{"CMD", "/bin/gots-tsnet", "-probe", "tcp://127.0.0.1:8080"}, cfg.HealthCheckTest())
+}
+
+func TestGenerateHealthCheck(t *testing.T) {
+	cfg := imageConfig()
+	cfg.HealthCheck = Ptr("/healthz")
+	cfg.HealthCheckInterval = Ptr("5s")
+	cfg.Services = []config.Service{{Name: "admin", Image: "adminer", Hostname: "app-admin", Port: 8080}}
+
+	// The check runs in the application's sidecar as it shares the application's network
+	services := generateCompose(t, cfg)
+	require.Equal(t, map[string]any{
+		"test":     []any{"CMD", "wget", "-q", "-O", "/dev/null", "http://127.0.0.1:80/healthz"},
+		"interval": "5s",
+		"timeout":  "2.5s",
+		"retries":  3,
+	}, services["ts-app"]["healthcheck"])
+	require.NotContains(t, services["ts-app-admin"], "healthcheck")
+	require.NotContains(t, services["app"], "healthcheck")
+
+	tsnet := tsnetConfig(t)
+	tsnet.HealthCheck = Ptr(config.HealthCheckTCP)
+	tsnet.HealthCheckRetries = Ptr(5)
+	services = generateCompose(t, tsnet)
+	require.Equal(t, map[string]any{
+		"test":     []any{"CMD", "/bin/gots-tsnet", "-probe", "tcp://127.0.0.1:80"},
+		"interval": "10s",
+		"timeout":  "5s",
+		"retries":  5,
+	}, services["app"]["healthcheck"])
+}
+
+func TestValidateHealthCheck(t *testing.T) {
+	cfg := validGoConfig(t)
+	cfg.HealthCheck = Ptr("healthz")
+	cfg.HealthCheckInterval = Ptr("soon")
+	cfg.HealthCheckRetries = Ptr(0)
+	require.Equal(t, []config.FieldError{
+		{Field: "HealthCheck", Message: `"healthz" must be an HTTP path (starting with /) or tcp`},
+		{Field: "HealthCheckInterval", Message: `"soon" isn't a duration (e.g. 10s)`},
+		{Field: "HealthCheckRetries", Message: "must be at least 1"},
+	}, cfg.Validate())
+}
@@ -0,0 +1,162 @@
+package config_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// imageConfig returns a complete dockerimage configuration for "app". The tests change the fields they're about.
+func imageConfig() config.Config {
+	return config.Config{
+		Type:           builder.AppTypeDockerImage,
+		DockerImage:    Ptr("app"),
+		DockerHostname: Ptr("app"),
+		Port:           Ptr(80),
+		Funnel:         Ptr(false),
+		WorkDir:        Ptr("/src"),
+	}
+}
+
+// goConfig returns a complete go configuration for "app" built from ./cmd/app. The tests change the fields they're
+// about.
+func goConfig() config.Config {
+	return config.Config{
+		Type:           builder.AppTypeGo,
+		DockerImage:    Ptr("app"),
+		DockerHostname: Ptr("app"),
+		ExecName:       Ptr("app"),
+		GoCompilePath:  Ptr("./cmd/app"),
+		Port:           Ptr(80),
+		Funnel:         Ptr(false),
+		WorkDir:        Ptr("/src"),
+	}
+}
+
+// goModDir returns a temporary directory with a go.mod for Go 1.25.1
+func goModDir(t *testing.T) string {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.25.1\n"), 0644))
+	return dir
+}
+
+// validGoConfig returns a complete go configuration whose WorkDir contains a main package in ./cmd/app
+func validGoConfig(t *testing.T) config.Config {
+	workDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(workDir, "cmd", "app"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "cmd", "app", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workDir, "cmd", "app", "main_test.go"), []byte("package main_test\n"), 0644))
+
+	cfg := goConfig()
+	cfg.DockerImage = Ptr("registry.example.com:5000/team/app:v1.2")
+	cfg.WorkDir = Ptr(workDir)
+	cfg.Port = Ptr(8080)
+	cfg.ExecArgs = []string{}
+	cfg.DockerVolumes = []config.Volume{{DockerDir: "/data", HostDir: workDir}}
+	return cfg
+}
+
+// systemdConfig returns a go configuration that runs as a systemd service with every kind of handler
+func systemdConfig() config.Config {
+	cfg := goConfig()
+	cfg.ExecArgs = []string{"-greeting", "hello world"}
+	cfg.Backend = Ptr(config.BackendSystemd)
+	cfg.Port = Ptr(8080)
+	cfg.Funnel = Ptr(true)
+	cfg.DockerVolumes = []config.Volume{}
+	cfg.EnvFiles = []string{".env"}
+	cfg.Handlers = []config.Handler{
+		{ListenPort: 443, Type: config.HandlerProxy, Path: "/", Target: "8080"},
+		{ListenPort: 8443, Type: config.HandlerText, Path: "/", Target: "100%"},
+		{ListenPort: 2222, Type: config.HandlerTCP, Target: "22"},
+	}
+	return cfg
+}
+
+// tsnetConfig returns a go configuration in tsnet mode with a service on the application's node and one on its own
+func tsnetConfig(t *testing.T) config.Config {
+	cfg := goConfig()
+	cfg.ExecArgs = []string{"-port", "80"}
+	cfg.GoTsnet = Ptr(true)
+	cfg.DockerVolumes = []config.Volume{{DockerDir: "/data", HostDir: "/srv/data"}}
+	cfg.WorkDir = Ptr(goModDir(t))
+	cfg.TailscaleTags = []string{"server"}
+	cfg.Services = []config.Service{
+		{Name: "db", Image: "postgres:17"},
+		{Name: "admin", Image: "adminer", Hostname: "app-admin", Port: 8080},
+	}
+	return cfg
+}
+
+// formatConfig returns a configuration that uses every kind of value
+func formatConfig() config.Config {
+	cfg := imageConfig()
+	cfg.Version = config.CurrentVersion
+	cfg.DockerImage = Ptr("nginx")
+	cfg.DockerHostname = Ptr("web")
+	cfg.Funnel = Ptr(true)
+	cfg.DockerVolumes = []config.Volume{{DockerDir: "/data", HostDir: "/srv/data"}}
+	cfg.Services = []config.Service{
+		{Name: "db", Image: "postgres:17", Env: []config.EnvVar{{Name: "POSTGRES_PASSWORD", Value: "${PASSWORD}"}, {Name: "DEBUG", Value: "true"}}},
+	}
+	cfg.Handlers = []config.Handler{{ListenPort: 443, Type: config.HandlerText, Path: "/", Target: "say \"hi\"\n"}}
+	cfg.EnvFiles = []string{"app.env"}
+	return cfg
+}
+
+// terminal hides the reader's ReadRune so input is read like it is from a terminal
+func terminal(input string) io.Reader {
+	return struct{ io.Reader }{strings.NewReader(input)}
+}
+
+// generate generates cfg in a temporary directory and returns it
+func generate(t *testing.T, cfg config.Config) string {
+	dir := t.TempDir()
+	require.NoError(t, cfg.Generate(dir))
+	return dir
+}
+
+// readGenerated returns the contents of the generated file name in dir
+func readGenerated(t *testing.T, dir string, name string) string {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+// generateCompose generates cfg and returns the services of its docker-compose.yaml
+func generateCompose(t *testing.T, cfg config.Config) map[string]map[string]any {
+	return parseCompose(t, []byte(readGenerated(t, generate(t, cfg), "docker-compose.yaml")))
+}
+
+// generateServeConfig generates cfg and returns its parsed serve.config
+func generateServeConfig(t *testing.T, cfg config.Config) map[string]any {
+	data := readGenerated(t, generate(t, cfg), "serve.config")
+	serve := map[string]any{}
+	require.NoError(t, json.Unmarshal([]byte(data), &serve), data)
+	return serve
+}
+
+// parseCompose parses a generated docker-compose.yaml and returns its services
+func parseCompose(t *testing.T, data []byte) map[string]map[string]any {
+	compose := struct {
+		Services map[string]map[string]any
+	}{}
+	require.NoError(t, yaml.Unmarshal(data, &compose))
+	return compose.Services
+}
+
+func keys[V any](m map[string]V) []string {
+	ret := []string{}
+	for k := range m {
+		ret = append(ret, k)
+	}
+	return ret
+}
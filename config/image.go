@@ -0,0 +1,52 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/efarrer/gots/config/builder"
+)
+
+// BuildsImage returns true if gots builds the application's image (go and dockerfile applications run in containers)
+func (c Config) BuildsImage() bool {
+	return !c.Systemd() && (c.Type == builder.AppTypeGo || c.Type == builder.AppTypeDockerFile)
+}
+
+// ImageRepository returns the application's image without its tag (or digest)
+func (c Config) ImageRepository() string {
+	image := Deref(c.DockerImage)
+	if i := strings.Index(image, "@"); i != -1 {
+		image = image[:i]
+	}
+	// A colon after the last slash starts the tag (one before it is a registry's port)
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// ImageTagged returns the application's image with the given tag
+func (c Config) ImageTagged(tag string) string {
+	return c.ImageRepository() + ":" + tag
+}
+
+// RegistryImage returns the name of the application's image in its Registry. Empty if there isn't a registry.
+func (c Config) RegistryImage() string {
+	if Deref(c.Registry) == "" {
+		return ""
+	}
+	return c.inRegistry(Deref(c.DockerImage))
+}
+
+// RegistryImageTagged returns the name of the application's image in its Registry with the given tag
+func (c Config) RegistryImageTagged(tag string) string {
+	return c.inRegistry(c.ImageTagged(tag))
+}
+
+// inRegistry returns the image's name in the Registry. A registry that's already in the image's name is replaced.
+func (c Config) inRegistry(image string) string {
+	// Like docker, the first part of the name is a registry if it looks like a host
+	if first, rest, ok := strings.Cut(image, "/"); ok && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		image = rest
+	}
+	return strings.TrimSuffix(Deref(c.Registry), "/") + "/" + image
+}
@@ -0,0 +1,47 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageRepository(t *testing.T) {
+	for image, repository := range map[string]string{
+		"app":                             "app",
+		"app:stable":                      "app",
+		"registry:5000/team/app":          "registry:5000/team/app",
+		"registry:5000/team/app:stable":   "registry:5000/team/app",
+		"app@sha256:0123456789abcdef0123": "app",
+	} {
+		cfg := config.Config{DockerImage: Ptr(image)}
+		require.Equal(t, repository, cfg.ImageRepository(), image)
+	}
+	cfg := config.Config{DockerImage: Ptr("registry:5000/app:stable")}
+	require.Equal(t, "registry:5000/app:abc1234", cfg.ImageTagged("abc1234"))
+}
+
+func TestRegistryImage(t *testing.T) {
+	cfg := config.Config{DockerImage: Ptr("app:stable")}
+	require.Equal(t, "", cfg.RegistryImage())
+
+	cfg.Registry = Ptr("ghcr.io/me/")
+	require.Equal(t, "ghcr.io/me/app:stable", cfg.RegistryImage())
+	require.Equal(t, "ghcr.io/me/app:abc1234", cfg.RegistryImageTagged("abc1234"))
+
+	// The image's own registry is replaced
+	cfg.DockerImage = Ptr("registry.example.com:5000/team/app")
+	require.Equal(t, "ghcr.io/me/team/app", cfg.RegistryImage())
+}
+
+func TestValidateRegistry(t *testing.T) {
+	cfg := validGoConfig(t)
+	cfg.Registry = Ptr("localhost:5000")
+	require.Empty(t, cfg.Validate())
+
+	cfg.Registry = Ptr("https://localhost:5000")
+	require.Equal(t, []config.FieldError{
+		{Field: "Registry", Message: `"https://localhost:5000/team/app:v1.2" isn't a valid image name`},
+	}, cfg.Validate())
+}
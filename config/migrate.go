@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/efarrer/gots/config/builder"
+)
+
+// CurrentVersion is the schema version of the configuration written by this version of gots. Configurations without a
+// Version predate versioning and are version 0.
+const CurrentVersion = 1
+
+// migrations upgrade the configuration one version at a time. migrations[i] upgrades version i to version i+1.
+var migrations = []func(c *Config){
+	migrateV0ToV1,
+}
+
+// migrateV0ToV1 upgrades unversioned configurations
+func migrateV0ToV1(c *Config) {
+	// CompileCommand was replaced by GoCompilePath
+	if len(c.DeprecatedCompileCommand) > 0 {
+		c.GoCompilePath = &c.DeprecatedCompileCommand[len(c.DeprecatedCompileCommand)-1]
+		c.DeprecatedCompileCommand = nil
+	}
+	// Before there were multiple types everything was go
+	if c.Type == "" {
+		c.Type = builder.AppTypeGo
+	}
+
+	// For go both the DockerImage and the DockerHostname are the same as the exec name
+	if c.Type == builder.AppTypeGo && c.DockerImage == nil {
+		c.DockerImage = c.ExecName
+	}
+	if c.Type == builder.AppTypeGo && c.DockerHostname == nil {
+		c.DockerHostname = c.ExecName
+	}
+	// The image type was renamed to dockerimage
+	if c.Type == "image" {
+		c.Type = builder.AppTypeDockerImage
+	}
+}
+
+// NeedsMigration returns true if the configuration is older than CurrentVersion
+func (c *Config) NeedsMigration() bool {
+	return c.Version < CurrentVersion
+}
+
+// Migrate upgrades the configuration to CurrentVersion. Configurations written by a newer gots are an error.
+func (c *Config) Migrate() error {
+	if c.Version > CurrentVersion {
+		return fmt.Errorf("Configuration version %d is newer than the supported version %d, upgrade gots\n", c.Version, CurrentVersion)
+	}
+	if c.Version < 0 {
+		return fmt.Errorf("Invalid configuration version %d\n", c.Version)
+	}
+	for ; c.Version < CurrentVersion; c.Version++ {
+		migrations[c.Version](c)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateV0ToV1CompileCommand(t *testing.T) {
+	cfg, err := config.Parse([]byte(`{"ExecName": "app", "CompileCommand": ["go", "build", "./cmd/app"]}`))
+	require.NoError(t, err)
+	require.Equal(t, 0, cfg.Version)
+
+	require.NoError(t, cfg.Migrate())
+	require.Equal(t, 1, cfg.Version)
+	require.Equal(t, builder.AppTypeGo, cfg.Type)
+	require.Equal(t, "./cmd/app", *cfg.GoCompilePath)
+	require.Nil(t, cfg.DeprecatedCompileCommand)
+	require.Equal(t, "app", *cfg.DockerImage)
+	require.Equal(t, "app", *cfg.DockerHostname)
+}
+
+func TestMigrateV0ToV1ImageType(t *testing.T) {
+	cfg, err := config.Parse([]byte(`{"Type": "image", "DockerImage": "nginx", "DockerHostname": "web"}`))
+	require.NoError(t, err)
+
+	require.NoError(t, cfg.Migrate())
+	require.Equal(t, builder.AppTypeDockerImage, cfg.Type)
+	require.Equal(t, "nginx", *cfg.DockerImage)
+	require.Equal(t, "web", *cfg.DockerHostname)
+}
+
+func TestMigrateCurrentVersion(t *testing.T) {
+	// Migrations aren't rerun on an up to date configuration
+	cfg := config.Config{Version: config.CurrentVersion, Type: builder.AppTypeGo, ExecName: Ptr("app")}
+	require.False(t, cfg.NeedsMigration())
+
+	require.NoError(t, cfg.Migrate())
+	require.Nil(t, cfg.DockerImage)
+	require.Nil(t, cfg.DockerHostname)
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	cfg := config.Config{Version: config.CurrentVersion + 1}
+	require.Error(t, cfg.Migrate())
+}
+
+func TestParseMalformed(t *testing.T) {
+	_, err := config.Parse([]byte("{\n  \"ExecName\": \"app\",\n  \"Port\": 80,,\n}"))
+	require.ErrorContains(t, err, "line 3 column 14")
+
+	_, err = config.Parse([]byte("{\n  \"Port\": \"80\"\n}"))
+	require.ErrorContains(t, err, "line 2 column 14")
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// RestartNo never restarts the application
+	RestartNo = "no"
+	// RestartAlways restarts the application whenever it exits
+	RestartAlways = "always"
+	// RestartOnFailure restarts the application when it exits with an error
+	RestartOnFailure = "on-failure"
+	// RestartUnlessStopped restarts the application whenever it exits unless it was stopped
+	RestartUnlessStopped = "unless-stopped"
+)
+
+// RestartPolicies are the supported values of RestartPolicy
+var RestartPolicies = []string{RestartNo, RestartAlways, RestartOnFailure, RestartUnlessStopped}
+
+// memorySize matches a memory size in bytes with an optional unit (e.g. 512m or 2g)
+var memorySize = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// Resources limits the resources used by the application. Empty values aren't limited.
+type Resources struct {
+	// Memory is the maximum amount of memory (e.g. 512m or 2g)
+	Memory *string `gots:"go,dockerimage,dockerfile,optional" json:"Memory,omitempty"`
+	// CPUs is the number of CPUs that can be used (e.g. 0.5 or 2)
+	CPUs *string `gots:"go,dockerimage,dockerfile,optional" json:"CPUs,omitempty"`
+	// NoFile is the maximum number of open files (0 for the default)
+	NoFile *int `gots:"go,dockerimage,dockerfile,optional" json:"NoFile,omitempty"`
+	// StopTimeout is how long the application has to stop before it's killed (e.g. 30s)
+	StopTimeout *string `gots:"go,dockerimage,dockerfile,optional" json:"StopTimeout,omitempty"`
+}
+
+// RestartPolicySafe returns the application's restart policy
+func (c Config) RestartPolicySafe() string {
+	if Deref(c.RestartPolicy) == "" {
+		return RestartUnlessStopped
+	}
+	return *c.RestartPolicy
+}
+
+// MemoryLimit returns the application's memory limit. Empty if there isn't one.
+func (c Config) MemoryLimit() string {
+	return Deref(Deref(c.Resources).Memory)
+}
+
+// CPULimit returns the number of CPUs the application can use. Empty if there isn't a limit.
+func (c Config) CPULimit() string {
+	return Deref(Deref(c.Resources).CPUs)
+}
+
+// NoFileLimit returns the maximum number of files the application can open. 0 if there isn't a limit.
+func (c Config) NoFileLimit() int {
+	return Deref(Deref(c.Resources).NoFile)
+}
+
+// StopTimeout returns how long the application has to stop before it's killed. Empty for the default.
+func (c Config) StopTimeout() string {
+	return Deref(Deref(c.Resources).StopTimeout)
+}
+
+// SystemdRestart returns the systemd Restart= setting for the restart policy. systemd doesn't restart stopped units
+// so unless-stopped is the same as always.
+func (u unit) SystemdRestart() string {
+	if u.RestartPolicySafe() == RestartUnlessStopped {
+		return RestartAlways
+	}
+	return u.RestartPolicySafe()
+}
+
+// SystemdMemoryMax returns the systemd MemoryMax= setting for the memory limit, which uses upper case units and
+// doesn't have a unit for bytes
+func (u unit) SystemdMemoryMax() string {
+	return strings.TrimSuffix(strings.ToUpper(u.MemoryLimit()), "B")
+}
+
+// SystemdCPUQuota returns the systemd CPUQuota= setting for the CPU limit. Empty if there isn't one.
+func (u unit) SystemdCPUQuota() string {
+	cpus, err := strconv.ParseFloat(u.CPULimit(), 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.Itoa(int(math.Round(cpus*100))) + "%"
+}
+
+// SystemdTimeoutStopSec returns the systemd TimeoutStopSec= setting for the stop timeout. Empty if there isn't one.
+func (u unit) SystemdTimeoutStopSec() string {
+	timeout, err := time.ParseDuration(u.StopTimeout())
+	if err != nil {
+		return ""
+	}
+	return strconv.Itoa(int(math.Ceil(timeout.Seconds()))) + "s"
+}
+
+// validateResources checks the restart policy and resource limits
+func (c Config) validateResources(add func(string, string, ...any)) {
+	if c.RestartPolicy != nil && !slices.Contains(RestartPolicies, *c.RestartPolicy) {
+		add("RestartPolicy", "must be one of %s", strings.Join(RestartPolicies, ", "))
+	}
+	if memory := c.MemoryLimit(); memory != "" && !memorySize.MatchString(memory) {
+		add("Resources.Memory", "%q isn't a memory size (e.g. 512m or 2g)", memory)
+	}
+	if cpus := c.CPULimit(); cpus != "" {
+		if n, err := strconv.ParseFloat(cpus, 64); err != nil || n <= 0 {
+			add("Resources.CPUs", "%q isn't a number of CPUs (e.g. 0.5 or 2)", cpus)
+		}
+	}
+	if c.NoFileLimit() < 0 {
+		add("Resources.NoFile", "can't be negative")
+	}
+	if timeout := c.StopTimeout(); timeout != "" {
+		if d, err := time.ParseDuration(timeout); err != nil || d <= 0 {
+			add("Resources.StopTimeout", "%q isn't a duration (e.g. 30s)", timeout)
+		}
+	}
+}
+
+// String describes the limits for the configuration wizard's summary of changes
+func (r Resources) String() string {
+	limits := []string{}
+	if Deref(r.Memory) != "" {
+		limits = append(limits, "memory "+*r.Memory)
+	}
+	if Deref(r.CPUs) != "" {
+		limits = append(limits, "CPUs "+*r.CPUs)
+	}
+	if Deref(r.NoFile) != 0 {
+		limits = append(limits, fmt.Sprintf("open files %d", *r.NoFile))
+	}
+	if Deref(r.StopTimeout) != "" {
+		limits = append(limits, "stop timeout "+*r.StopTimeout)
+	}
+	if len(limits) == 0 {
+		return "none"
+	}
+	return strings.Join(limits, ", ")
+}
@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateResources(t *testing.T) {
+	cfg := imageConfig()
+	services := generateCompose(t, cfg)
+	require.Equal(t, "unless-stopped", services["app"]["restart"])
+	require.NotContains(t, services["app"], "mem_limit")
+
+	cfg.RestartPolicy = Ptr(config.RestartNo)
+	cfg.Resources = &config.Resources{
+		Memory:      Ptr("512m"),
+		CPUs:        Ptr("1.5"),
+		NoFile:      Ptr(4096),
+		StopTimeout: Ptr("30s"),
+	}
+	services = generateCompose(t, cfg)
+	// "no" must be quoted so it isn't false
+	require.Equal(t, "no", services["app"]["restart"])
+	require.Equal(t, "512m", services["app"]["mem_limit"])
+	require.Equal(t, 1.5, services["app"]["cpus"])
+	require.Equal(t, map[string]any{"nofile": 4096}, services["app"]["ulimits"])
+	require.Equal(t, "30s", services["app"]["stop_grace_period"])
+	// The sidecar always comes back
+	require.Equal(t, "unless-stopped", services["ts-app"]["restart"])
+}
+
+func TestGenerateSystemdResources(t *testing.T) {
+	cfg := systemdConfig()
+	service := readGenerated(t, generate(t, cfg), "gots-app.service")
+	require.Contains(t, service, "Restart=always\n")
+	require.NotContains(t, service, "MemoryMax=")
+
+	cfg.RestartPolicy = Ptr(config.RestartOnFailure)
+	cfg.Resources = &config.Resources{
+		Memory:      Ptr("2gb"),
+		CPUs:        Ptr("0.5"),
+		NoFile:      Ptr(4096),
+		StopTimeout: Ptr("1m30s"),
+	}
+	service = readGenerated(t, generate(t, cfg), "gots-app.service")
+	require.Contains(t, service, "Restart=on-failure\nMemoryMax=2G\nCPUQuota=50%\nLimitNOFILE=4096\nTimeoutStopSec=90s\n")
+}
+
+func TestValidateResources(t *testing.T) {
+	cfg := validGoConfig(t)
+	cfg.RestartPolicy = Ptr("sometimes")
+	cfg.Resources = &config.Resources{
+		Memory:      Ptr("lots"),
+		CPUs:        Ptr("0"),
+		NoFile:      Ptr(-1),
+		StopTimeout: Ptr("30"),
+	}
+	require.Equal(t, []config.FieldError{
+		{Field: "RestartPolicy", Message: "must be one of no, always, on-failure, unless-stopped"},
+		{Field: "Resources.Memory", Message: `"lots" isn't a memory size (e.g. 512m or 2g)`},
+		{Field: "Resources.CPUs", Message: `"0" isn't a number of CPUs (e.g. 0.5 or 2)`},
+		{Field: "Resources.NoFile", Message: "can't be negative"},
+		{Field: "Resources.StopTimeout", Message: `"30" isn't a duration (e.g. 30s)`},
+	}, cfg.Validate())
+}
+
+func TestRequestResources(t *testing.T) {
+	answers := config.Config{Resources: &config.Resources{Memory: Ptr("1g"), NoFile: Ptr(1024)}}
+	require.Equal(t, map[string]any{"Memory": "1g", "NoFile": 1024}, answers.Answers())
+
+	b := builder.New(strings.NewReader(""), builder.AppTypeDockerImage).AssumeYes()
+	for name, value := range answers.Answers() {
+		b.Answer(name, value)
+	}
+	cfg := config.Config{Type: builder.AppTypeDockerImage}
+	require.NoError(t, cfg.RequestMissingConfiguration(b))
+	require.Equal(t, "1g", *cfg.Resources.Memory)
+	require.Nil(t, cfg.Resources.CPUs)
+	require.Equal(t, 1024, *cfg.Resources.NoFile)
+	require.Nil(t, cfg.Resources.StopTimeout)
+	require.Equal(t, config.RestartUnlessStopped, *cfg.RestartPolicy)
+
+	// There are no limits if none are entered
+	b = builder.New(strings.NewReader(""), builder.AppTypeDockerImage).AssumeYes()
+	cfg = config.Config{Type: builder.AppTypeDockerImage}
+	require.NoError(t, cfg.RequestMissingConfiguration(b))
+	require.Nil(t, cfg.Resources)
+}
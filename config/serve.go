@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+const (
+	// HandlerProxy proxies HTTPS requests to a local port
+	HandlerProxy = "proxy"
+	// HandlerTCP forwards raw TCP connections to a local port
+	HandlerTCP = "tcp"
+	// HandlerText serves static text
+	HandlerText = "text"
+	// HandlerFile serves a file or directory from the host
+	HandlerFile = "file"
+)
+
+// FunnelPorts are the only ports that Tailscale allows funnels on
+var FunnelPorts = []int{443, 8443, 10000}
+
+// Handler is a route served on the application's tailnet node
+type Handler struct {
+	// ListenPort is the port on the tailnet node
+	ListenPort int
+	// Type is one of HandlerProxy, HandlerTCP, HandlerText, or HandlerFile
+	Type string
+	// Path is the URL path prefix that is handled. Unused for HandlerTCP.
+	Path string `json:"Path,omitempty"`
+	// Target is the local port for HandlerProxy and HandlerTCP, the text for HandlerText, and the host path for
+	// HandlerFile
+	Target string
+}
+
+// Listener is a port on the tailnet node along with the handlers that serve it
+type Listener struct {
+	Port int
+	// TCPForward is the local address that raw TCP connections are forwarded to. Empty for HTTPS listeners.
+	TCPForward string
+	Handlers   []Handler
+	Funnel     bool
+}
+
+// HandlersToStrings flattens handlers into the strings requested by the configuration wizard
+func HandlersToStrings(hs []Handler) []string {
+	if hs == nil {
+		return nil
+	}
+	ret := []string{}
+	for _, h := range hs {
+		path := h.Path
+		if path == "" {
+			path = "-"
+		}
+		ret = append(ret, strconv.Itoa(h.ListenPort), h.Type, path, h.Target)
+	}
+	return ret
+}
+
+// StringsToHandlers converts the strings requested by the configuration wizard into handlers
+func StringsToHandlers(strs []string) ([]Handler, error) {
+	if strs == nil {
+		return nil, nil
+	}
+	ret := []Handler{}
+	for i := 0; i+3 < len(strs); i += 4 {
+		port, err := strconv.Atoi(strs[i])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid listen port %s\n", strs[i])
+		}
+		h := Handler{ListenPort: port, Type: strs[i+1], Path: strs[i+2], Target: strs[i+3]}
+		if h.Path == "-" {
+			h.Path = ""
+		}
+		switch h.Type {
+		case HandlerProxy, HandlerTCP:
+			if _, err := strconv.Atoi(h.Target); err != nil {
+				return nil, fmt.Errorf("Invalid local port %s for %s handler\n", h.Target, h.Type)
+			}
+		case HandlerText, HandlerFile:
+		default:
+			return nil, fmt.Errorf("Unknown handler type %s\n", h.Type)
+		}
+		if h.Type != HandlerTCP && h.Path == "" {
+			h.Path = "/"
+		}
+		ret = append(ret, h)
+		if conflict := handlerConflict(ret, len(ret)-1); conflict != "" {
+			return nil, fmt.Errorf("Invalid %s handler on %s\n", h.Type, conflict)
+		}
+	}
+	return ret, nil
+}
+
+// handlerConflict returns why handlers[i] can't listen on the same port as the handlers before it, empty if it can. A
+// port either forwards raw TCP connections to a single local port or serves HTTPS routes.
+func handlerConflict(handlers []Handler, i int) string {
+	h := handlers[i]
+	for _, other := range handlers[:i] {
+		if other.ListenPort != h.ListenPort {
+			continue
+		}
+		if other.Type == HandlerTCP {
+			return fmt.Sprintf("port %d which already forwards TCP", h.ListenPort)
+		}
+		if h.Type == HandlerTCP {
+			return fmt.Sprintf("port %d which already serves HTTPS routes", h.ListenPort)
+		}
+	}
+	return ""
+}
+
+// ServeHandlers returns the configured handlers or if there are none the default of proxying / on port 443 to Port
+func (c Config) ServeHandlers() []Handler {
+	if len(c.Handlers) > 0 {
+		return c.Handlers
+	}
+	return []Handler{{ListenPort: 443, Type: HandlerProxy, Path: "/", Target: strconv.Itoa(Deref(c.Port))}}
+}
+
+// Listeners groups the handlers by the port they listen on
+func (c Config) Listeners() []Listener {
+	listeners := []Listener{}
+	for _, h := range c.ServeHandlers() {
+		i := slices.IndexFunc(listeners, func(l Listener) bool { return l.Port == h.ListenPort })
+		if i == -1 {
+			listeners = append(listeners, Listener{
+				Port:   h.ListenPort,
+				Funnel: Deref(c.Funnel) && slices.Contains(FunnelPorts, h.ListenPort),
+			})
+			i = len(listeners) - 1
+		}
+		if h.Type == HandlerTCP {
+			listeners[i].TCPForward = "127.0.0.1:" + h.Target
+		} else {
+			listeners[i].Handlers = append(listeners[i].Handlers, h)
+		}
+	}
+	return listeners
+}
+
+// WebListeners returns the listeners that serve HTTPS
+func (c Config) WebListeners() []Listener {
+	return FilterSlice(c.Listeners(), func(l Listener) bool { return l.TCPForward != "" })
+}
+
+// ServedFiles returns the host paths served by HandlerFile handlers
+func (c Config) ServedFiles() []string {
+	files := []string{}
+	for _, h := range c.ServeHandlers() {
+		if h.Type == HandlerFile {
+			files = append(files, h.Target)
+		}
+	}
+	return files
+}
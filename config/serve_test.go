@@ -0,0 +1,87 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringsToHandlers(t *testing.T) {
+	expected := []config.Handler{
+		{ListenPort: 443, Type: config.HandlerProxy, Path: "/", Target: "8080"},
+		{ListenPort: 2222, Type: config.HandlerTCP, Target: "22"},
+		{ListenPort: 8443, Type: config.HandlerText, Path: "/hello", Target: "hi"},
+	}
+
+	res, err := config.StringsToHandlers(config.HandlersToStrings(expected))
+	require.NoError(t, err)
+	require.Equal(t, expected, res)
+
+	_, err = config.StringsToHandlers([]string{"443", "nope", "/", "80"})
+	require.Error(t, err)
+	_, err = config.StringsToHandlers([]string{"443", "proxy", "/", "eighty"})
+	require.Error(t, err)
+
+	// A port either forwards TCP or serves HTTPS routes
+	_, err = config.StringsToHandlers([]string{"443", "proxy", "/", "80", "443", "tcp", "-", "22"})
+	require.EqualError(t, err, "Invalid tcp handler on port 443 which already serves HTTPS routes\n")
+	_, err = config.StringsToHandlers([]string{"2222", "tcp", "-", "22", "2222", "tcp", "-", "23"})
+	require.EqualError(t, err, "Invalid tcp handler on port 2222 which already forwards TCP\n")
+}
+
+func TestServeConfig(t *testing.T) {
+	cfg := imageConfig()
+	cfg.Funnel = Ptr(true)
+
+	t.Run("defaults to proxying / to Port", func(t *testing.T) {
+		require.Equal(t, map[string]any{
+			"TCP": map[string]any{"443": map[string]any{"HTTPS": true}},
+			"Web": map[string]any{
+				"${TS_CERT_DOMAIN}:443": map[string]any{
+					"Handlers": map[string]any{"/": map[string]any{"Proxy": "http://127.0.0.1:80"}},
+				},
+			},
+			"AllowFunnel": map[string]any{"${TS_CERT_DOMAIN}:443": true},
+		}, generateServeConfig(t, cfg))
+	})
+
+	t.Run("multiple handlers", func(t *testing.T) {
+		cfg.Handlers = []config.Handler{
+			{ListenPort: 443, Type: config.HandlerProxy, Path: "/", Target: "80"},
+			{ListenPort: 443, Type: config.HandlerProxy, Path: "/api", Target: "9000"},
+			{ListenPort: 8443, Type: config.HandlerText, Path: "/", Target: "say \"hi\""},
+			{ListenPort: 10001, Type: config.HandlerFile, Path: "/static", Target: "/srv/static"},
+			{ListenPort: 2222, Type: config.HandlerTCP, Target: "22"},
+		}
+		require.Equal(t, map[string]any{
+			"TCP": map[string]any{
+				"443":   map[string]any{"HTTPS": true},
+				"8443":  map[string]any{"HTTPS": true},
+				"10001": map[string]any{"HTTPS": true},
+				"2222":  map[string]any{"TCPForward": "127.0.0.1:22"},
+			},
+			"Web": map[string]any{
+				"${TS_CERT_DOMAIN}:443": map[string]any{
+					"Handlers": map[string]any{
+						"/":    map[string]any{"Proxy": "http://127.0.0.1:80"},
+						"/api": map[string]any{"Proxy": "http://127.0.0.1:9000"},
+					},
+				},
+				"${TS_CERT_DOMAIN}:8443": map[string]any{
+					"Handlers": map[string]any{"/": map[string]any{"Text": "say \"hi\""}},
+				},
+				"${TS_CERT_DOMAIN}:10001": map[string]any{
+					"Handlers": map[string]any{"/static": map[string]any{"Path": "/srv/static"}},
+				},
+			},
+			"AllowFunnel": map[string]any{
+				"${TS_CERT_DOMAIN}:443":   true,
+				"${TS_CERT_DOMAIN}:8443":  true,
+				"${TS_CERT_DOMAIN}:10001": false,
+				"${TS_CERT_DOMAIN}:2222":  false,
+			},
+		}, generateServeConfig(t, cfg))
+		require.Equal(t, []string{"/srv/static"}, cfg.Nodes()[0].Files)
+	})
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/efarrer/gots/config/builder"
+)
+
+// ServiceField returns the name that a service's field that isn't part of the list of services (e.g. its
+// DockerVolumes) is requested and answered by, e.g. Services.db.DockerVolumes. -reset Services resets them too.
+func ServiceField(service string, field string) string {
+	return "Services." + service + "." + field
+}
+
+// serviceAnswers adds the answers for the services' fields that aren't part of the list of services
+func serviceAnswers(answers map[string]any, services []Service) {
+	for _, svc := range services {
+		answers[ServiceField(svc.Name, "Funnel")] = svc.Funnel
+		answers[ServiceField(svc.Name, "DockerVolumes")] = nonNil(VolumesToStrings(svc.DockerVolumes))
+		answers[ServiceField(svc.Name, "Env")] = nonNil(EnvToStrings(svc.Env))
+	}
+}
+
+// nonNil returns an empty slice instead of nil so an existing empty value isn't requested again
+func nonNil(strs []string) []string {
+	if strs == nil {
+		return []string{}
+	}
+	return strs
+}
+
+// requestServiceDetails requests the fields of each of the services that aren't part of the list of services. The
+// values of the existing service with the same name are kept so they're only requested for new services (or when
+// editing).
+func requestServiceDetails(b *builder.Builder, ats mapset.Set[string], existing []Service, services []Service) []Service {
+	current := map[string]Service{}
+	for _, svc := range existing {
+		current[svc.Name] = svc
+	}
+	for i, svc := range services {
+		old, ok := current[svc.Name]
+
+		// A funnel needs the service's own node and port
+		svc.Funnel = false
+		if svc.Hostname != "" && svc.Port != 0 {
+			var funnel []bool
+			if ok {
+				funnel = []bool{old.Funnel}
+			}
+			funnel = builder.RequestSliceRaw(b, ServiceField(svc.Name, "Funnel"), funnel, []bool{false},
+				fmt.Sprintf("Should a Tailscale funnel be started for \"%s\"? (y/n): ", svc.Name), nil, ats)
+			svc.Funnel = len(funnel) == 1 && funnel[0]
+		}
+
+		var volumes []string
+		if ok {
+			volumes = nonNil(VolumesToStrings(old.DockerVolumes))
+		}
+		svc.DockerVolumes = StringsToVolumes(builder.RequestSliceRaw(b, ServiceField(svc.Name, "DockerVolumes"), volumes, []string{},
+			fmt.Sprintf("Enter the volumes to mount in \"%s\"\n", svc.Name),
+			[]string{
+				"Docker dir (absolute path) %d: ",
+				"Host dir (absolute path) %d: ",
+			},
+			ats,
+		))
+		if len(svc.DockerVolumes) == 0 {
+			svc.DockerVolumes = nil
+		}
+
+		var env []string
+		if ok {
+			env = nonNil(EnvToStrings(old.Env))
+		}
+		svc.Env = StringsToEnv(builder.RequestSliceRaw(b, ServiceField(svc.Name, "Env"), env, []string{},
+			fmt.Sprintf("Enter the environment variables to pass to \"%s\". Use ${VAR} in a value to read VAR from the environment when starting (e.g. for secrets).\n", svc.Name),
+			[]string{
+				"Name %d: ",
+				"Value %d: ",
+			},
+			ats,
+		))
+		if len(svc.Env) == 0 {
+			svc.Env = nil
+		}
+		services[i] = svc
+	}
+	return services
+}
@@ -0,0 +1,108 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/stretchr/testify/require"
+)
+
+// withServices returns a complete dockerimage configuration with the services, like one loaded from a .gots
+func withServices(t *testing.T, services []config.Service) *config.Config {
+	answers := config.Config{
+		DockerImage:        Ptr("nginx"),
+		DockerHostname:     Ptr("web"),
+		WorkDir:            Ptr(t.TempDir()),
+		TargetPlatform:     Ptr("linux/amd64"),
+		Port:               Ptr(80),
+		Funnel:             Ptr(false),
+		TailscaleEphemeral: Ptr(false),
+		TailscaleAuthOnce:  Ptr(false),
+		Resources:          &config.Resources{Memory: Ptr("512m"), CPUs: Ptr("1"), NoFile: Ptr(1024), StopTimeout: Ptr("10s")},
+	}
+	b := builder.New(terminal(""), builder.AppTypeDockerImage).AssumeYes()
+	for name, value := range answers.Answers() {
+		b.Answer(name, value)
+	}
+	cfg := &config.Config{Type: builder.AppTypeDockerImage, Funnel: Ptr(false), TailscaleEphemeral: Ptr(false), TailscaleAuthOnce: Ptr(false)}
+	require.NoError(t, cfg.RequestMissingConfiguration(b))
+	cfg.Services = services
+	return cfg
+}
+
+func TestRequestServices(t *testing.T) {
+	db := config.Service{
+		Name:          "db",
+		Image:         "postgres",
+		Hostname:      "db",
+		Port:          5432,
+		Funnel:        true,
+		DockerVolumes: []config.Volume{{DockerDir: "/var/lib/postgresql/data", HostDir: "/srv/db"}},
+		Env:           []config.EnvVar{{Name: "A", Value: "b"}},
+	}
+
+	t.Run("answers keep every field", func(t *testing.T) {
+		answers := config.Config{Services: []config.Service{db}}
+		b := builder.New(terminal(""), builder.AppTypeDockerImage).AssumeYes()
+		for name, value := range answers.Answers() {
+			b.Answer(name, value)
+		}
+		cfg := withServices(t, nil)
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		require.Equal(t, []config.Service{db}, cfg.Services)
+	})
+
+	t.Run("running the wizard again keeps every field", func(t *testing.T) {
+		cfg := withServices(t, []config.Service{db})
+		require.NoError(t, cfg.RequestMissingConfiguration(builder.New(terminal(""), builder.AppTypeDockerImage).AssumeYes()))
+		require.Equal(t, []config.Service{db}, cfg.Services)
+	})
+
+	t.Run("editing the list keeps the other fields", func(t *testing.T) {
+		cfg := withServices(t, []config.Service{db})
+		b := builder.New(terminal(""), builder.AppTypeDockerImage).AssumeYes().
+			Answer("Services", []string{"db", "postgres:16", "db", "5432"})
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		updated := db
+		updated.Image = "postgres:16"
+		require.Equal(t, []config.Service{updated}, cfg.Services)
+	})
+
+	t.Run("new services are asked for the other fields", func(t *testing.T) {
+		cfg := withServices(t, []config.Service{db})
+		b := builder.New(terminal("y\n/data\n/srv/cache\n\nPASSWORD\n${REDIS_PASSWORD}\n\n"), builder.AppTypeDockerImage).AssumeYes().
+			Answer("Services", []string{"db", "postgres", "db", "5432", "cache", "redis", "cache", "6379"})
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		require.Equal(t, []config.Service{db, {
+			Name:          "cache",
+			Image:         "redis",
+			Hostname:      "cache",
+			Port:          6379,
+			Funnel:        true,
+			DockerVolumes: []config.Volume{{DockerDir: "/data", HostDir: "/srv/cache"}},
+			Env:           []config.EnvVar{{Name: "PASSWORD", Value: "${REDIS_PASSWORD}"}},
+		}}, cfg.Services)
+	})
+
+	t.Run("reset Services asks for the other fields again", func(t *testing.T) {
+		cfg := withServices(t, []config.Service{db})
+		// Keep the list, turn off the funnel, remove the volume, and add an env var
+		b := builder.New(terminal("\n\nn\n0\n\n\n\nB\nc\n\n"), builder.AppTypeDockerImage).AssumeYes().Reset("Services")
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		updated := db
+		updated.Funnel = false
+		updated.DockerVolumes = nil
+		updated.Env = []config.EnvVar{{Name: "A", Value: "b"}, {Name: "B", Value: "c"}}
+		require.Equal(t, []config.Service{updated}, cfg.Services)
+	})
+
+	t.Run("reset a service's env", func(t *testing.T) {
+		cfg := withServices(t, []config.Service{db})
+		b := builder.New(terminal("0\n\nC\nd\n\n"), builder.AppTypeDockerImage).AssumeYes().Reset(config.ServiceField("db", "Env"))
+		require.NoError(t, cfg.RequestMissingConfiguration(b))
+		updated := db
+		updated.Env = []config.EnvVar{{Name: "C", Value: "d"}}
+		require.Equal(t, []config.Service{updated}, cfg.Services)
+	})
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/launcher"
+)
+
+const (
+	// BackendDocker runs the application in containers with docker or podman compose
+	BackendDocker = "docker"
+	// BackendSystemd runs a go application as a host process with systemd user units
+	BackendSystemd = "systemd"
+)
+
+// Systemd returns true if the application is run with systemd user units instead of containers
+func (c Config) Systemd() bool {
+	return c.Type == builder.AppTypeGo && Deref(c.Backend) == BackendSystemd
+}
+
+// Unit returns the name of the systemd unit that runs the executable
+func (c Config) Unit() string {
+	return "gots-" + Deref(c.DockerHostname) + ".service"
+}
+
+// TailscaledUnit returns the name of the systemd unit that runs the application's tailscaled. Empty in tsnet mode as
+// the executable joins the tailnet itself.
+func (c Config) TailscaledUnit() string {
+	if c.Tsnet() {
+		return ""
+	}
+	return "gots-" + Deref(c.DockerHostname) + "-tailscaled.service"
+}
+
+// Units returns the names of the application's systemd units
+func (c Config) Units() []string {
+	if c.Tsnet() {
+		return []string{c.Unit()}
+	}
+	return []string{c.TailscaledUnit(), c.Unit()}
+}
+
+// unit is the data used to generate the systemd units
+type unit struct {
+	Config
+	// Dir is the absolute path of the directory that the units are generated in
+	Dir string
+}
+
+// ExecStart returns the command line that runs the executable (with the launcher in tsnet mode)
+func (u unit) ExecStart() string {
+	cmd := append([]string{filepath.Join(u.Dir, Deref(u.ExecName))}, u.ExecArgs...)
+	if u.Tsnet() {
+		cmd = u.tsnetCmd(filepath.Join(u.Dir, launcher.Name, launcher.Name), filepath.Join(u.Dir, "tailscale"),
+			filepath.Join(u.Dir, "serve.config"), cmd)
+	}
+	return systemdCommand(cmd)
+}
+
+// TailscaledExecStart returns the command line that runs a userspace tailscaled that only serves the application
+func (u unit) TailscaledExecStart() string {
+	cmd := []string{"tailscaled", "--tun=userspace-networking", "--socket=" + u.socket(),
+		"--statedir=" + filepath.Join(u.Dir, "tailscale"), "--port=0"}
+	if u.TailscaleEphemeralSafe() {
+		cmd = append(cmd, "--state=mem:")
+	}
+	return systemdCommand(cmd)
+}
+
+// TailscaleUp returns the command line that logs the tailscaled in. TS_AUTHKEY is read from the unit's environment.
+func (u unit) TailscaleUp() string {
+	cmd := systemdCommand(u.tailscale("up", "--hostname="+Deref(u.DockerHostname)))
+	if args := u.TailscaleExtraArgs(); args != "" {
+		cmd += " " + systemdCommand([]string{args})
+	}
+	return cmd + " --auth-key=" + u.TailscaleAuthKey()
+}
+
+// ServeCommands returns the command lines that configure the tailscaled to serve the application's routes
+func (u unit) ServeCommands() []string {
+	cmds := []string{systemdCommand(u.tailscale("serve", "reset"))}
+	for _, l := range u.Listeners() {
+		subcommand := "serve"
+		if l.Funnel {
+			subcommand = "funnel"
+		}
+		port := strconv.Itoa(l.Port)
+		if l.TCPForward != "" {
+			cmds = append(cmds, systemdCommand(u.tailscale(subcommand, "--bg", "--tcp="+port, "tcp://"+l.TCPForward)))
+			continue
+		}
+		for _, h := range l.Handlers {
+			target := h.Target
+			switch h.Type {
+			case HandlerProxy:
+				target = "http://127.0.0.1:" + h.Target
+			case HandlerText:
+				target = "text:" + h.Target
+			}
+			cmds = append(cmds, systemdCommand(u.tailscale(subcommand, "--bg", "--https="+port, "--set-path="+h.Path, target)))
+		}
+	}
+	return cmds
+}
+
+// socket returns the path of the tailscaled's socket
+func (u unit) socket() string {
+	return filepath.Join(u.Dir, "tailscaled.sock")
+}
+
+// tailscale returns a tailscale CLI command that talks to the application's tailscaled
+func (u unit) tailscale(args ...string) []string {
+	return append([]string{"tailscale", "--socket=" + u.socket()}, args...)
+}
+
+// systemdCommand quotes a command for a unit's Exec lines. % and $ are escaped so systemd doesn't expand them.
+func systemdCommand(cmd []string) string {
+	quoted := []string{}
+	for _, arg := range cmd {
+		arg = strings.ReplaceAll(arg, "%", "%%")
+		arg = strings.ReplaceAll(arg, "$", "$$")
+		if arg == "" || strings.ContainsAny(arg, " \t\"'\\;") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// unitFiles returns the templates that generate the systemd units in dstDir
+func (c Config) unitFiles(dstDir string) ([]templates, error) {
+	dir, err := filepath.Abs(dstDir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the absolute path of %s %w\n", dstDir, err)
+	}
+	ts := []templates{{
+		dstFileName:     c.Unit(),
+		srcTemplateName: "gots.service.template",
+		srcTemplate:     serviceUnitTemplate,
+		data:            unit{Config: c, Dir: dir},
+	}}
+	if !c.Tsnet() {
+		ts = append(ts, templates{
+			dstFileName:     c.TailscaledUnit(),
+			srcTemplateName: "tailscaled.service.template",
+			srcTemplate:     tailscaledUnitTemplate,
+			data:            unit{Config: c, Dir: dir},
+		})
+	}
+	return ts, nil
+}
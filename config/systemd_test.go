@@ -0,0 +1,71 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateSystemd(t *testing.T) {
+	cfg := systemdConfig()
+	dir := generate(t, cfg)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.ElementsMatch(t, []string{"gots-app.service", "gots-app-tailscaled.service"}, names)
+
+	service := readGenerated(t, dir, "gots-app.service")
+	require.Contains(t, service, "Wants=gots-app-tailscaled.service\n")
+	require.Contains(t, service, "WorkingDirectory=/src\n")
+	require.Contains(t, service, "EnvironmentFile=/src/.env\n")
+	require.Contains(t, service, "ExecStart="+filepath.Join(dir, "app")+` -greeting "hello world"`+"\n")
+
+	tailscaled := readGenerated(t, dir, "gots-app-tailscaled.service")
+	socket := "--socket=" + filepath.Join(dir, "tailscaled.sock")
+	require.Contains(t, tailscaled, "ExecStart=tailscaled --tun=userspace-networking "+socket)
+	require.Contains(t, tailscaled, "ExecStartPost=tailscale "+socket+" up --hostname=app --auth-key=${TS_AUTHKEY}\n")
+	require.Contains(t, tailscaled, "ExecStartPost=tailscale "+socket+" serve reset\n")
+	require.Contains(t, tailscaled, "ExecStartPost=tailscale "+socket+" funnel --bg --https=443 --set-path=/ http://127.0.0.1:8080\n")
+	require.Contains(t, tailscaled, "ExecStartPost=tailscale "+socket+" funnel --bg --https=8443 --set-path=/ text:100%%\n")
+	require.Contains(t, tailscaled, "ExecStartPost=tailscale "+socket+" serve --bg --tcp=2222 tcp://127.0.0.1:22\n")
+}
+
+func TestGenerateSystemdTsnet(t *testing.T) {
+	cfg := systemdConfig()
+	cfg.GoTsnet = Ptr(true)
+	dir := generate(t, cfg)
+
+	_, err := os.Stat(filepath.Join(dir, "gots-app-tailscaled.service"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(dir, "serve.config"))
+	require.NoError(t, err)
+
+	service := readGenerated(t, dir, "gots-app.service")
+	require.NotContains(t, service, "Wants=")
+	require.Contains(t, service, "EnvironmentFile=-"+filepath.Join(dir, "tailscale.env")+"\n")
+	require.Contains(t, service, "ExecStart="+filepath.Join(dir, "gots-tsnet", "gots-tsnet")+" -hostname app -state-dir "+
+		filepath.Join(dir, "tailscale")+" -serve-config "+filepath.Join(dir, "serve.config")+" -- "+filepath.Join(dir, "app"))
+}
+
+func TestValidateSystemd(t *testing.T) {
+	cfg := systemdConfig()
+	cfg.DockerVolumes = []config.Volume{{DockerDir: "/data", HostDir: "/srv/data"}}
+	cfg.Services = []config.Service{{Name: "db", Image: "postgres"}}
+
+	fields := []string{}
+	for _, err := range cfg.Validate() {
+		fields = append(fields, err.Field)
+	}
+	require.Contains(t, fields, "DockerVolumes")
+	require.Contains(t, fields, "Services")
+
+	cfg.Backend = Ptr("kubernetes")
+	require.Contains(t, cfg.Validate(), config.FieldError{Field: "Backend", Message: "must be docker or systemd"})
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TailscaleTagsSafe returns the ACL tags to advertise, adding the tag: prefix to any that are missing it
+func (c Config) TailscaleTagsSafe() []string {
+	tags := []string{}
+	for _, tag := range c.TailscaleTags {
+		if !strings.HasPrefix(tag, "tag:") {
+			tag = "tag:" + tag
+		}
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// TailscaleAuthKey returns the TS_AUTHKEY for the tailscale sidecars. The key is read from the host's TS_AUTHKEY when
+// the application is started. OAuth client secrets need parameters to say what kind of auth key to create.
+func (c Config) TailscaleAuthKey() string {
+	if !Deref(c.TailscaleOAuth) {
+		return "${TS_AUTHKEY}"
+	}
+	return fmt.Sprintf("${TS_AUTHKEY}?ephemeral=%t&preauthorized=true", Deref(c.TailscaleEphemeral))
+}
+
+// TailscaleExtraArgs returns the extra arguments passed to tailscale up by the tailscale sidecars
+func (c Config) TailscaleExtraArgs() string {
+	if len(c.TailscaleTags) == 0 {
+		return ""
+	}
+	return "--advertise-tags=" + strings.Join(c.TailscaleTagsSafe(), ",")
+}
+
+// TailscaleEphemeralSafe returns true if the tailnet nodes are ephemeral
+func (c Config) TailscaleEphemeralSafe() bool {
+	return Deref(c.TailscaleEphemeral)
+}
+
+// TailscaleAuthOnceSafe returns true if the auth key is only used when a node isn't logged in
+func (c Config) TailscaleAuthOnceSafe() bool {
+	return Deref(c.TailscaleAuthOnce)
+}
+
+// SidecarVolumes returns the volumes mounted in the node's tailscale sidecar
+func (c Config) SidecarVolumes(n Node) []string {
+	volumes := []string{}
+	if c.Remote() {
+		// The serve config is inlined as a compose config
+		if n.StateDir != "" {
+			volumes = append(volumes, "ts-"+n.Hostname+"-state:/var/lib/tailscale")
+		}
+	} else {
+		if n.StateDir != "" {
+			volumes = append(volumes, n.StateDir+":/var/lib/tailscale")
+		}
+		if n.ServeConfig != "" {
+			volumes = append(volumes, "${PWD}/"+n.ServeConfig+":/config/serve.config")
+		}
+	}
+	for _, file := range n.Files {
+		volumes = append(volumes, file+":"+file+":ro")
+	}
+	return volumes
+}
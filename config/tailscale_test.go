@@ -0,0 +1,58 @@
+package config_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTailscaleExtraArgs(t *testing.T) {
+	cfg := config.Config{}
+	require.Equal(t, "", cfg.TailscaleExtraArgs())
+
+	cfg.TailscaleTags = []string{"tag:server", "web"}
+	require.Equal(t, "--advertise-tags=tag:server,tag:web", cfg.TailscaleExtraArgs())
+}
+
+func TestTailscaleAuthKey(t *testing.T) {
+	cfg := config.Config{}
+	require.Equal(t, "${TS_AUTHKEY}", cfg.TailscaleAuthKey())
+
+	cfg.TailscaleOAuth = Ptr(true)
+	require.Equal(t, "${TS_AUTHKEY}?ephemeral=false&preauthorized=true", cfg.TailscaleAuthKey())
+
+	cfg.TailscaleEphemeral = Ptr(true)
+	require.Equal(t, "${TS_AUTHKEY}?ephemeral=true&preauthorized=true", cfg.TailscaleAuthKey())
+}
+
+func TestGenerateTailscaleOptions(t *testing.T) {
+	cfg := imageConfig()
+	cfg.TailscaleTags = []string{"tag:server"}
+	cfg.TailscaleEphemeral = Ptr(true)
+	cfg.TailscaleAuthOnce = Ptr(true)
+	cfg.TailscaleOAuth = Ptr(true)
+
+	services := generateCompose(t, cfg)
+	require.Equal(t, []any{
+		"TS_AUTHKEY=${TS_AUTHKEY}?ephemeral=true&preauthorized=true",
+		"TS_SERVE_CONFIG=/config/serve.config",
+		"TS_EXTRA_ARGS=--advertise-tags=tag:server",
+		"TS_AUTH_ONCE=true",
+	}, services["ts-app"]["environment"])
+	// Ephemeral nodes don't keep any state
+	require.Equal(t, []any{"${PWD}/serve.config:/config/serve.config"}, services["ts-app"]["volumes"])
+}
+
+func TestValidateTailscaleOptions(t *testing.T) {
+	cfg := validGoConfig(t)
+	cfg.TailscaleOAuth = Ptr(true)
+	require.Equal(t, []config.FieldError{
+		{Field: "TailscaleTags", Message: "are required when TS_AUTHKEY is an OAuth client secret"},
+	}, cfg.Validate())
+
+	cfg.TailscaleTags = []string{"server", "tag:bad tag"}
+	require.Equal(t, []config.FieldError{
+		{Field: "TailscaleTags[1]", Message: `"tag:bad tag" isn't a valid ACL tag`},
+	}, cfg.Validate())
+}
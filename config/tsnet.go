@@ -0,0 +1,73 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/launcher"
+)
+
+// Tsnet returns true if the Go executable joins the tailnet itself with the gots-tsnet launcher instead of using a
+// tailscale sidecar container
+func (c Config) Tsnet() bool {
+	return c.Type == builder.AppTypeGo && Deref(c.GoTsnet)
+}
+
+// NodeService returns the name of the docker compose service that runs the tailnet node with the given hostname. The
+// application's node runs in the application's container in tsnet mode.
+func (c Config) NodeService(hostname string) string {
+	if c.Tsnet() && hostname == Deref(c.DockerHostname) {
+		return hostname
+	}
+	return "ts-" + hostname
+}
+
+// SidecarNodes returns the nodes that are run as tailscale sidecar containers
+func (c Config) SidecarNodes() []Node {
+	return FilterSlice(c.Nodes(), func(n Node) bool { return c.NodeService(n.Hostname) != "ts-"+n.Hostname })
+}
+
+// TsnetBuildImage returns the golang image used to build the launcher in Docker
+func (c Config) TsnetBuildImage() string {
+	return "golang:" + launcher.GoVersion
+}
+
+// TsnetCmd returns the launcher and its arguments followed by the executable and its arguments
+func (c Config) TsnetCmd() []string {
+	return c.tsnetCmd("/bin/"+launcher.Name, "", "/config/serve.config", c.Cmd())
+}
+
+// tsnetCmd returns the command that runs the launcher at path which runs cmd. The launcher's default state dir is used
+// if stateDir is empty.
+func (c Config) tsnetCmd(path string, stateDir string, serveConfig string, cmd []string) []string {
+	ret := []string{path, "-hostname", Deref(c.DockerHostname)}
+	if stateDir != "" {
+		ret = append(ret, "-state-dir", stateDir)
+	}
+	if c.Nodes()[0].ServeConfig != "" {
+		ret = append(ret, "-serve-config", serveConfig)
+	}
+	if c.TailscaleEphemeralSafe() {
+		ret = append(ret, "-ephemeral")
+	}
+	if len(c.TailscaleTags) > 0 {
+		ret = append(ret, "-tags", strings.Join(c.TailscaleTagsSafe(), ","))
+	}
+	if Deref(c.TailscaleOAuth) {
+		ret = append(ret, "-oauth")
+	}
+	return append(append(ret, "--"), cmd...)
+}
+
+// AppVolumes returns the volumes mounted in the application's container. In tsnet mode these include the volumes that
+// would be mounted in its tailscale sidecar.
+func (c Config) AppVolumes() []string {
+	volumes := []string{}
+	if c.Tsnet() {
+		volumes = append(volumes, c.SidecarVolumes(c.Nodes()[0])...)
+	}
+	for _, v := range c.DockerVolumes {
+		volumes = append(volumes, v.HostDir+":"+v.DockerDir)
+	}
+	return volumes
+}
@@ -0,0 +1,66 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efarrer/gots/config/builder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTsnetCmd(t *testing.T) {
+	cfg := tsnetConfig(t)
+	require.Equal(t, []string{
+		"/bin/gots-tsnet", "-hostname", "app", "-serve-config", "/config/serve.config", "-tags", "tag:server",
+		"--", "/bin/app", "-port", "80",
+	}, cfg.TsnetCmd())
+
+	// Only go applications can use tsnet
+	cfg.Type = builder.AppTypeDockerImage
+	require.False(t, cfg.Tsnet())
+	require.Equal(t, "ts-app", cfg.NodeService("app"))
+}
+
+func TestGenerateTsnet(t *testing.T) {
+	cfg := tsnetConfig(t)
+	dir := generate(t, cfg)
+
+	services := parseCompose(t, []byte(readGenerated(t, dir, "docker-compose.yaml")))
+	// Only the service with its own node has a sidecar
+	require.ElementsMatch(t, []string{"app", "db", "admin", "ts-app-admin"}, keys(services))
+	require.NotContains(t, services["app"], "network_mode")
+	require.NotContains(t, services["app"], "cap_add")
+	require.Contains(t, services["app"]["environment"], "TS_AUTHKEY=${TS_AUTHKEY}")
+	require.Equal(t, []any{
+		filepath.Join(*cfg.WorkDir, ".tailscale") + ":/var/lib/tailscale",
+		"${PWD}/serve.config:/config/serve.config",
+		"/srv/data:/data",
+	}, services["app"]["volumes"])
+	require.Equal(t, "service:app", services["db"]["network_mode"])
+	require.Equal(t, "service:ts-app-admin", services["admin"]["network_mode"])
+
+	_, err := os.Stat(filepath.Join(dir, "gots-tsnet", "main.go"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, "gots-tsnet", "go.sum"))
+	require.NoError(t, err)
+
+	dockerfile := readGenerated(t, dir, "Dockerfile")
+	require.Contains(t, dockerfile, "COPY ./gots-tsnet/gots-tsnet /bin/\n")
+	require.Contains(t, dockerfile, `CMD ["/bin/gots-tsnet","-hostname","app",`)
+
+	cfg.GoDockerBuild = Ptr(true)
+	dockerfile = readGenerated(t, generate(t, cfg), "Dockerfile")
+	require.Contains(t, dockerfile, "FROM --platform=$BUILDPLATFORM golang:1.26 AS tsnet-build\n")
+	require.Contains(t, dockerfile, "GOFLAGS=-mod=readonly go build -trimpath -o /out/gots-tsnet .\n")
+	require.Contains(t, dockerfile, "COPY --from=tsnet-build /out/gots-tsnet /bin/\n")
+}
+
+func TestGenerateTsnetRemote(t *testing.T) {
+	cfg := tsnetConfig(t)
+	cfg.DockerHost = Ptr("ssh://me@server")
+
+	services := generateCompose(t, cfg)
+	require.Equal(t, []any{"ts-app-state:/var/lib/tailscale", "/srv/data:/data"}, services["app"]["volumes"])
+	require.Equal(t, []any{map[string]any{"source": "serve-app", "target": "/config/serve.config"}}, services["app"]["configs"])
+}
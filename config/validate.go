@@ -0,0 +1,222 @@
+package config
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/engine"
+)
+
+// FieldError is a problem with a single configuration field
+type FieldError struct {
+	// Field is the name of the field, nested fields are named like Services[0].Hostname
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// dnsLabel matches a valid DNS label (which is what tailnet hostnames must be)
+var dnsLabel = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// tailscaleTag matches a Tailscale ACL tag
+var tailscaleTag = regexp.MustCompile(`^tag:[A-Za-z][A-Za-z0-9-]*$`)
+
+// imageReference matches a container image reference ([registry[:port]/]name[:tag][@digest])
+var imageReference = regexp.MustCompile(`^` +
+	`(?:[a-zA-Z0-9]+(?:[.-][a-zA-Z0-9]+)*(?::[0-9]+)?/)?` + // registry
+	`[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*` + // name
+	`(?::[A-Za-z0-9_][A-Za-z0-9_.-]{0,127})?` + // tag
+	`(?:@[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,})?` + // digest
+	`$`)
+
+// Validate checks the configuration and returns a FieldError for each problem. It returns nil if the configuration is
+// valid.
+func (c *Config) Validate() []FieldError {
+	errs := []FieldError{}
+	add := func(field string, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if !slices.Contains([]string{builder.AppTypeGo, builder.AppTypeDockerImage, builder.AppTypeDockerFile}, c.Type) {
+		add("Type", "unknown application type %q", c.Type)
+		return errs
+	}
+
+	for _, name := range GetNilFieldNames(*c) {
+		tags := builder.GetFieldTags(c, name)
+		if tags.Contains(c.Type) && !tags.Contains(builder.TagOptional) {
+			add(name, "is required for %s applications", c.Type)
+		}
+	}
+
+	if c.DockerHostname != nil {
+		validateHostname(add, "DockerHostname", *c.DockerHostname)
+	}
+	if c.DockerImage != nil {
+		validateImage(add, "DockerImage", *c.DockerImage)
+	}
+	if c.GoRuntimeImage != nil {
+		validateImage(add, "GoRuntimeImage", *c.GoRuntimeImage)
+	}
+	if c.Port != nil {
+		validatePort(add, "Port", *c.Port)
+	}
+	if c.Engine != nil && *c.Engine != engine.Docker && *c.Engine != engine.Podman {
+		add("Engine", "must be %s or %s", engine.Docker, engine.Podman)
+	}
+	if c.RegistryImage() != "" {
+		validateImage(add, "Registry", c.RegistryImage())
+	}
+	if c.TargetPlatform != nil && len(strings.Split(*c.TargetPlatform, "/")) < 2 {
+		add("TargetPlatform", "%q isn't of the form os/arch", *c.TargetPlatform)
+	}
+	if c.WorkDir != nil {
+		if info, err := os.Stat(*c.WorkDir); err != nil || !info.IsDir() {
+			add("WorkDir", "%s isn't a directory", *c.WorkDir)
+		}
+	}
+	if c.Type == builder.AppTypeGo && c.GoCompilePath != nil && c.WorkDir != nil {
+		validateMainPackage(add, "GoCompilePath", *c.WorkDir, *c.GoCompilePath)
+	}
+
+	validateVolumes(add, "DockerVolumes", c.DockerVolumes, !c.Remote())
+
+	if c.Backend != nil && *c.Backend != BackendDocker && *c.Backend != BackendSystemd {
+		add("Backend", "must be %s or %s", BackendDocker, BackendSystemd)
+	}
+	if c.Systemd() {
+		if len(c.DockerVolumes) > 0 {
+			add("DockerVolumes", "aren't supported by the %s backend", BackendSystemd)
+		}
+		if len(c.Services) > 0 {
+			add("Services", "aren't supported by the %s backend", BackendSystemd)
+		}
+		if Deref(c.Registry) != "" {
+			add("Registry", "isn't supported by the %s backend", BackendSystemd)
+		}
+	}
+
+	for i, tag := range c.TailscaleTagsSafe() {
+		if !tailscaleTag.MatchString(tag) {
+			add(fmt.Sprintf("TailscaleTags[%d]", i), "%q isn't a valid ACL tag", c.TailscaleTags[i])
+		}
+	}
+	if Deref(c.TailscaleOAuth) && len(c.TailscaleTags) == 0 {
+		add("TailscaleTags", "are required when TS_AUTHKEY is an OAuth client secret")
+	}
+
+	c.validateHealthCheck(add)
+	c.validateResources(add)
+
+	for i, h := range c.Handlers {
+		field := fmt.Sprintf("Handlers[%d]", i)
+		validatePort(add, field+".ListenPort", h.ListenPort)
+		switch h.Type {
+		case HandlerProxy, HandlerTCP:
+			port, err := strconv.Atoi(h.Target)
+			if err != nil {
+				add(field+".Target", "%q isn't a port", h.Target)
+			} else {
+				validatePort(add, field+".Target", port)
+			}
+		case HandlerText, HandlerFile:
+		default:
+			add(field+".Type", "%q isn't one of %s, %s, %s, or %s", h.Type, HandlerProxy, HandlerTCP, HandlerText, HandlerFile)
+		}
+		if conflict := handlerConflict(c.Handlers, i); conflict != "" {
+			add(field+".ListenPort", "a %s handler can't listen on %s", h.Type, conflict)
+		}
+	}
+
+	for i, svc := range c.Services {
+		field := fmt.Sprintf("Services[%d]", i)
+		if !dnsLabel.MatchString(svc.Name) {
+			add(field+".Name", "%q isn't a valid service name", svc.Name)
+		}
+		validateImage(add, field+".Image", svc.Image)
+		if svc.Hostname != "" {
+			validateHostname(add, field+".Hostname", svc.Hostname)
+		}
+		if svc.Port != 0 {
+			validatePort(add, field+".Port", svc.Port)
+		}
+		validateVolumes(add, field+".DockerVolumes", svc.DockerVolumes, !c.Remote())
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateHostname(add func(string, string, ...any), field string, hostname string) {
+	if !dnsLabel.MatchString(hostname) {
+		add(field, "%q isn't a valid DNS label (letters, digits, and hyphens, at most 63 characters)", hostname)
+	}
+}
+
+func validateImage(add func(string, string, ...any), field string, image string) {
+	if !imageReference.MatchString(image) {
+		add(field, "%q isn't a valid image name", image)
+	}
+}
+
+func validatePort(add func(string, string, ...any), field string, port int) {
+	if port < 1 || port > 65535 {
+		add(field, "%d is out of range (1-65535)", port)
+	}
+}
+
+// validateVolumes checks that the volume paths are absolute and if the engine is local that the host directories exist
+func validateVolumes(add func(string, string, ...any), field string, volumes []Volume, local bool) {
+	for i, v := range volumes {
+		if !filepath.IsAbs(v.HostDir) {
+			add(fmt.Sprintf("%s[%d].HostDir", field, i), "%s isn't an absolute path", v.HostDir)
+		} else if _, err := os.Stat(v.HostDir); local && err != nil {
+			add(fmt.Sprintf("%s[%d].HostDir", field, i), "%s doesn't exist", v.HostDir)
+		}
+		if !filepath.IsAbs(v.DockerDir) {
+			add(fmt.Sprintf("%s[%d].DockerDir", field, i), "%s isn't an absolute path", v.DockerDir)
+		}
+	}
+}
+
+// validateMainPackage checks that a local GoCompilePath (relative to workDir) is a directory containing a main package.
+// Import paths are left to the Go compiler.
+func validateMainPackage(add func(string, string, ...any), field string, workDir string, compilePath string) {
+	if compilePath != "." && !strings.HasPrefix(compilePath, "./") && !strings.HasPrefix(compilePath, "../") &&
+		!filepath.IsAbs(compilePath) {
+		return
+	}
+	dir := compilePath
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(workDir, dir)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		add(field, "%s isn't a directory", compilePath)
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), filepath.Join(dir, name), nil, parser.PackageClauseOnly)
+		if err == nil && file.Name.Name == "main" {
+			return
+		}
+	}
+	add(field, "%s doesn't contain a main package", compilePath)
+}
@@ -0,0 +1,84 @@
+package config_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	cfg := validGoConfig(t)
+	require.Nil(t, cfg.Validate())
+}
+
+func TestValidateMissingFields(t *testing.T) {
+	cfg := validGoConfig(t)
+	cfg.Port = nil
+	cfg.ExecArgs = nil
+
+	require.ElementsMatch(t, []config.FieldError{
+		{Field: "ExecArgs", Message: "is required for go applications"},
+		{Field: "Port", Message: "is required for go applications"},
+	}, cfg.Validate())
+}
+
+func TestValidateFields(t *testing.T) {
+	cfg := validGoConfig(t)
+	cfg.DockerHostname = Ptr("my_app")
+	cfg.DockerImage = Ptr("App:latest")
+	cfg.Port = Ptr(70000)
+	cfg.GoCompilePath = Ptr("./")
+	cfg.DockerVolumes = []config.Volume{
+		{DockerDir: "data", HostDir: "relative"},
+		{DockerDir: "/data", HostDir: filepath.Join(*cfg.WorkDir, "missing")},
+	}
+	cfg.Services = []config.Service{{Name: "db", Image: "postgres:17", Hostname: "-db", Port: 0}}
+	cfg.Handlers = []config.Handler{{ListenPort: 443, Type: config.HandlerProxy, Path: "/", Target: "0"}}
+
+	fields := []string{}
+	for _, err := range cfg.Validate() {
+		fields = append(fields, err.Field)
+	}
+	require.ElementsMatch(t, []string{
+		"DockerHostname",
+		"DockerImage",
+		"Port",
+		"GoCompilePath",
+		"DockerVolumes[0].HostDir",
+		"DockerVolumes[0].DockerDir",
+		"DockerVolumes[1].HostDir",
+		"Services[0].Hostname",
+		"Handlers[0].Target",
+	}, fields)
+}
+
+func TestValidateHandlers(t *testing.T) {
+	cfg := validGoConfig(t)
+	cfg.Handlers = []config.Handler{
+		{ListenPort: 443, Type: config.HandlerProxy, Path: "/", Target: "8080"},
+		{ListenPort: 443, Type: config.HandlerTCP, Target: "22"},
+		{ListenPort: 2222, Type: config.HandlerTCP, Target: "22"},
+		{ListenPort: 2222, Type: config.HandlerText, Path: "/", Target: "hi"},
+		{ListenPort: 8443, Type: "prxy", Path: "/", Target: "8080"},
+	}
+	require.Equal(t, []config.FieldError{
+		{Field: "Handlers[1].ListenPort", Message: "a tcp handler can't listen on port 443 which already serves HTTPS routes"},
+		{Field: "Handlers[3].ListenPort", Message: "a text handler can't listen on port 2222 which already forwards TCP"},
+		{Field: "Handlers[4].Type", Message: `"prxy" isn't one of proxy, tcp, text, or file`},
+	}, cfg.Validate())
+}
+
+func TestValidateRemoteVolumes(t *testing.T) {
+	// Host directories are on the remote Docker host so they can't be checked
+	cfg := validGoConfig(t)
+	cfg.DockerHost = Ptr("ssh://server")
+	cfg.DockerVolumes = []config.Volume{{DockerDir: "/data", HostDir: "/srv/missing"}}
+	require.Nil(t, cfg.Validate())
+}
+
+func TestValidateType(t *testing.T) {
+	cfg := config.Config{Type: "rust"}
+	require.Equal(t, []config.FieldError{{Field: "Type", Message: `unknown application type "rust"`}}, cfg.Validate())
+}
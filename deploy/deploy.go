@@ -0,0 +1,384 @@
+package deploy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/launcher"
+	"github.com/efarrer/gots/run"
+	"github.com/efarrer/gots/tailscale"
+)
+
+// ErrMissingAuthKey is returned when the application isn't yet in the tailnet and TS_AUTHKEY isn't set
+var ErrMissingAuthKey = errors.New("TS_AUTHKEY environment variable must be set")
+
+// CommandTimeout limits how long the command run by a step can take
+var CommandTimeout = 10 * time.Minute
+
+// BuildTimeout limits how long building, shipping, pushing, or pulling an image can take
+var BuildTimeout = 30 * time.Minute
+
+// Step is a single named action taken while starting or stopping an application
+type Step struct {
+	Name string
+	// Cmd is the command that the step runs. Empty for steps that don't run a command.
+	Cmd []string
+	// Timeout limits how long the step can run. 0 for no limit.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// StepError reports which step failed along with any output captured from it
+type StepError struct {
+	Step   string
+	Stdout string
+	Stderr string
+	Err    error
+}
+
+func (e *StepError) Error() string {
+	msg := fmt.Sprintf("%s failed: %s", e.Step, e.Err)
+	if e.Stdout != "" {
+		msg += fmt.Sprintf("\nStdout:\n%s", e.Stdout)
+	}
+	if e.Stderr != "" {
+		msg += fmt.Sprintf("\nStderr:\n%s", e.Stderr)
+	}
+	return msg
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// prefix is written before each line of a step's streamed output
+func prefix(name string) string {
+	return name + " | "
+}
+
+// Command returns a Step that runs a command in dir with the extra environment variables env. The command's output is
+// streamed to the terminal and the last of it is reported if it fails.
+func Command(name string, dir string, env []string, cmd string, args ...string) Step {
+	return Step{
+		Name:    name,
+		Cmd:     append([]string{cmd}, args...),
+		Timeout: CommandTimeout,
+		Run: func(ctx context.Context) error {
+			result, err := run.Run(ctx, run.Cmd{Name: cmd, Args: args, Dir: dir, Env: env, Stream: true, Prefix: prefix(name)})
+			if err != nil {
+				return &StepError{Step: name, Stdout: result.Stdout, Stderr: result.Stderr, Err: err}
+			}
+			return nil
+		},
+	}
+}
+
+// Execute runs the steps in order stopping at the first failure or when ctx is canceled. The returned error is always
+// a *StepError.
+func Execute(ctx context.Context, steps []Step) error {
+	for _, step := range steps {
+		err := execute(ctx, step)
+		if err == nil {
+			continue
+		}
+		var stepErr *StepError
+		if errors.As(err, &stepErr) {
+			return err
+		}
+		return &StepError{Step: step.Name, Err: err}
+	}
+	return nil
+}
+
+// execute runs a single step within its timeout
+func execute(ctx context.Context, step Step) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
+	return step.Run(ctx)
+}
+
+// CheckAuthKey returns a Step that fails with ErrMissingAuthKey if any of the hostnames aren't in the tailnet and
+// TS_AUTHKEY isn't set
+func CheckAuthKey(hostnames ...string) Step {
+	return Step{
+		Name: "check tailnet for " + strings.Join(hostnames, ", "),
+		Run: func(ctx context.Context) error {
+			status, err := tailscale.GetStatus(ctx)
+			if err != nil {
+				return err
+			}
+			for _, hostname := range hostnames {
+				if status.FindPeer(hostname) == nil && os.Getenv("TS_AUTHKEY") == "" {
+					return fmt.Errorf("%w when creating %s", ErrMissingAuthKey, hostname)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// RequireAuthKey returns a Step that fails with ErrMissingAuthKey if TS_AUTHKEY isn't set. Ephemeral nodes log in
+// every time they start so they always need it.
+func RequireAuthKey() Step {
+	return Step{
+		Name: "check for TS_AUTHKEY",
+		Run: func(ctx context.Context) error {
+			if os.Getenv("TS_AUTHKEY") == "" {
+				return ErrMissingAuthKey
+			}
+			return nil
+		},
+	}
+}
+
+// CheckEnv returns a Step that fails if any of the host environment variables referenced by the configuration aren't
+// set
+func CheckEnv(names ...string) Step {
+	return Step{
+		Name: "check environment for " + strings.Join(names, ", "),
+		Run: func(ctx context.Context) error {
+			missing := []string{}
+			for _, name := range names {
+				if _, ok := os.LookupEnv(name); !ok {
+					missing = append(missing, name)
+				}
+			}
+			if len(missing) > 0 {
+				return fmt.Errorf("%s must be set in the environment", strings.Join(missing, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// composeArgs returns the container engine arguments for a compose command run against the targets
+func composeArgs(cfg *config.Config, targets []Target, command string, services func(Target) []string) []string {
+	args := cfg.ContainerEngine().Compose(ComposeProject(cfg), command)
+	if command == "up" {
+		args = append(args, "-d")
+	}
+	// Without any services compose operates on all of them
+	if isAll(cfg, targets) {
+		return args
+	}
+	for _, t := range targets {
+		args = append(args, services(t)...)
+	}
+	return args
+}
+
+// BuildSteps returns the steps that build the application's image with the local container engine. There are no
+// steps for dockerimage apps.
+func BuildSteps(cfg *config.Config, dir string) []Step {
+	steps := buildSteps(cfg, dir)
+	for i := range steps {
+		steps[i].Timeout = BuildTimeout
+	}
+	return steps
+}
+
+// buildSteps returns the steps that build the application's image
+func buildSteps(cfg *config.Config, dir string) []Step {
+	workDir := config.Deref(cfg.WorkDir)
+	image := config.Deref(cfg.DockerImage)
+	e := cfg.ContainerEngine().Name()
+	buildArgs := func(args ...string) []string {
+		ret := []string{"build", "--network=host"}
+		if cfg.TargetPlatform != nil {
+			ret = append(ret, "--platform", *cfg.TargetPlatform)
+		}
+		return append(ret, args...)
+	}
+
+	switch cfg.Type {
+	case builder.AppTypeGo:
+		launcherDir := filepath.Join(dir, launcher.Name)
+		if cfg.GoBuildsInDocker() {
+			// The generated Dockerfile builds the executable from the source directory and the launcher from its
+			// own build context
+			args := []string{"-f", filepath.Join(dir, "Dockerfile"), "-t", image}
+			if cfg.Tsnet() {
+				args = append(args, "--build-context", launcher.Name+"="+launcherDir)
+			}
+			return []Step{
+				Command(e+" build", workDir, nil, e, buildArgs(append(args, ".")...)...),
+			}
+		}
+		// systemd services run on this host so they aren't cross-compiled
+		goEnv := cfg.GoEnv()
+		if cfg.Systemd() {
+			goEnv = nil
+		}
+		steps := []Step{
+			Command("go build", workDir, goEnv, "go", "build", "-o", filepath.Join(dir, config.Deref(cfg.ExecName)), cfg.GoCompilePathSafe()),
+		}
+		if cfg.Tsnet() {
+			// The launcher's dependencies are pinned by its go.sum
+			env := append(goEnv, "CGO_ENABLED=0", "GOFLAGS=-mod=readonly")
+			steps = append(steps, Command("go build "+launcher.Name, launcherDir, env, "go", "build", "-trimpath", "-o", launcher.Name, "."))
+		}
+		if cfg.Systemd() {
+			return steps
+		}
+		return append(steps, Command(e+" build", dir, nil, e, buildArgs("-t", image, ".")...))
+	case builder.AppTypeDockerFile:
+		// Note that this builds the Dockerfile in the source directory not the one that we generate for Go programs
+		return []Step{
+			Command(e+" build", workDir, nil, e, buildArgs("-t", image, ".")...),
+		}
+	}
+	return nil
+}
+
+// ShipImage returns a Step that copies the locally built image to the remote container engine
+func ShipImage(cfg *config.Config) Step {
+	e := cfg.ContainerEngine()
+	image := config.Deref(cfg.DockerImage)
+	save := []string{e.Name(), "save", image}
+	load := []string{e.Name(), "load"}
+	name := "ship " + image
+	return Step{
+		Name:    name,
+		Cmd:     append(append(save, "|"), load...),
+		Timeout: BuildTimeout,
+		Run: func(ctx context.Context) error {
+			result, err := run.Pipe(ctx,
+				run.Cmd{Name: save[0], Args: save[1:], Env: e.Local().Env(), Stream: true, Prefix: prefix(name)},
+				run.Cmd{Name: load[0], Args: load[1:], Env: e.Env(), Stream: true, Prefix: prefix(name)},
+			)
+			if err != nil {
+				return &StepError{Step: name, Stdout: result.Stdout, Stderr: result.Stderr, Err: err}
+			}
+			return nil
+		},
+	}
+}
+
+// StartSteps returns the steps that build and start the targets whose generated files are in dir. If push is true
+// the application's image is pushed to its registry.
+func StartSteps(cfg *config.Config, dir string, targets []Target, push bool) []Step {
+	if cfg.Systemd() {
+		return systemdStartSteps(cfg, dir, targets)
+	}
+	app := config.Deref(cfg.DockerHostname)
+	e := cfg.ContainerEngine()
+
+	steps := []Step{CheckAuthKey(nodes(targets)...)}
+	if cfg.TailscaleEphemeralSafe() {
+		steps = []Step{RequireAuthKey()}
+	}
+	if names := cfg.EnvReferences(); len(names) > 0 {
+		steps = append(steps, CheckEnv(names...))
+	}
+	startsApp := slices.ContainsFunc(targets, func(t Target) bool { return t.Service == app })
+	r := &release{}
+	built := false
+	if startsApp {
+		build := BuildSteps(cfg, dir)
+		built = len(build) > 0
+		steps = append(steps, build...)
+		if built {
+			steps = append(steps, tagImage(cfg, r))
+		}
+		if built && push {
+			steps = append(steps, pushRelease(cfg, r))
+		}
+		// Images are built locally so they need to be copied to a remote engine, which can pull them once they're
+		// pushed
+		if built && e.Remote() {
+			if push {
+				steps = append(steps, PullSteps(cfg)...)
+			} else {
+				steps = append(steps, ShipImage(cfg))
+			}
+		}
+	}
+	service := func(t Target) []string { return []string{t.Service} }
+	steps = append(steps,
+		Command(e.Name()+" compose stop", dir, e.Env(), e.Name(), composeArgs(cfg, targets, "stop", service)...),
+		Command(e.Name()+" compose up", dir, e.Env(), e.Name(), composeArgs(cfg, targets, "up", service)...),
+	)
+	if built {
+		steps = append(steps, recordImage(cfg, dir, r))
+	}
+	if startsApp {
+		steps = append(steps, healthSteps(cfg)...)
+	}
+	return steps
+}
+
+// RedeploySteps returns the steps that rebuild the application and recreate only its container (or restart only its
+// systemd unit) whose generated files are in dir. Its tailscale sidecar (or tailscaled) is left running so the node
+// stays in the tailnet. Like StartSteps it then waits for the application to be ready if it has a health check.
+func RedeploySteps(cfg *config.Config, dir string) []Step {
+	steps := BuildSteps(cfg, dir)
+	if cfg.Systemd() {
+		steps = append(steps, systemctl("restart", cfg.Unit()))
+		return append(steps, healthSteps(cfg)...)
+	}
+	r := &release{}
+	steps = append(steps, tagImage(cfg, r))
+	if cfg.Remote() {
+		steps = append(steps, ShipImage(cfg))
+	}
+	steps = append(steps, recreateApp(cfg, dir), recordImage(cfg, dir, r))
+	return append(steps, healthSteps(cfg)...)
+}
+
+// StopSteps returns the steps that stop the targets whose generated files are in dir
+func StopSteps(cfg *config.Config, dir string, targets []Target) []Step {
+	if cfg.Systemd() {
+		return systemdStopSteps(cfg)
+	}
+	// A sidecar is only stopped with its service if no other service uses it
+	services := func(t Target) []string {
+		if t.OwnNode {
+			return []string{t.Service, t.Sidecar()}
+		}
+		return []string{t.Service}
+	}
+	e := cfg.ContainerEngine()
+	return []Step{
+		Command(e.Name()+" compose stop", dir, append(e.Env(), "TS_AUTHKEY="), e.Name(), composeArgs(cfg, targets, "stop", services)...),
+	}
+}
+
+// Start builds and starts the application whose generated files are in dir. If service isn't empty only that
+// service is started. If push is true the application's image is pushed to its registry.
+func Start(ctx context.Context, cfg *config.Config, dir string, service string, push bool) error {
+	targets, err := Targets(cfg, service)
+	if err != nil {
+		return err
+	}
+	if push {
+		err = checkPush(cfg)
+		if err != nil {
+			return err
+		}
+	}
+	return Execute(ctx, StartSteps(cfg, dir, targets, push))
+}
+
+// Stop stops the application whose generated files are in dir. If service isn't empty only that service is
+// stopped.
+func Stop(ctx context.Context, cfg *config.Config, dir string, service string) error {
+	targets, err := Targets(cfg, service)
+	if err != nil {
+		return err
+	}
+	return Execute(ctx, StopSteps(cfg, dir, targets))
+}
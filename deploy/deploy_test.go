@@ -0,0 +1,327 @@
+package deploy_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/run"
+	"github.com/efarrer/gots/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartSteps(t *testing.T) {
+	cfg := goConfig()
+
+	t.Run("go", func(t *testing.T) {
+		cfg.Type = builder.AppTypeGo
+		targets, err := deploy.Targets(cfg, "")
+		require.NoError(t, err)
+		require.Equal(t,
+			[]string{"check tailnet for app", "go build", "docker build", "tag app", "docker compose stop", "docker compose up", "record image history"},
+			stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+	})
+
+	t.Run("dockerfile", func(t *testing.T) {
+		cfg.Type = builder.AppTypeDockerFile
+		targets, err := deploy.Targets(cfg, "")
+		require.NoError(t, err)
+		require.Equal(t,
+			[]string{"check tailnet for app", "docker build", "tag app", "docker compose stop", "docker compose up", "record image history"},
+			stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+	})
+
+	t.Run("dockerimage", func(t *testing.T) {
+		cfg.Type = builder.AppTypeDockerImage
+		targets, err := deploy.Targets(cfg, "")
+		require.NoError(t, err)
+		require.Equal(t,
+			[]string{"check tailnet for app", "docker compose stop", "docker compose up"},
+			stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+	})
+}
+
+func TestServiceSteps(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.Services = []config.Service{
+		{Name: "db", Image: "postgres"},
+		{Name: "admin", Image: "adminer", Hostname: "app-admin"},
+	}
+
+	t.Run("all services", func(t *testing.T) {
+		targets, err := deploy.Targets(cfg, "")
+		require.NoError(t, err)
+		require.Equal(t,
+			[]string{"check tailnet for app, app-admin", "docker build", "tag app", "docker compose stop", "docker compose up", "record image history"},
+			stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+		require.Equal(t, [][]string{
+			{"docker", "build", "--network=host", "-t", "app", "."},
+			{"docker", "compose", "-p", "app", "stop"},
+			{"docker", "compose", "-p", "app", "up", "-d"},
+		}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+	})
+
+	t.Run("service sharing the application's node", func(t *testing.T) {
+		targets, err := deploy.Targets(cfg, "db")
+		require.NoError(t, err)
+		require.Equal(t, [][]string{
+			{"docker", "compose", "-p", "app", "stop", "db"},
+			{"docker", "compose", "-p", "app", "up", "-d", "db"},
+		}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+		require.Equal(t, [][]string{
+			{"docker", "compose", "-p", "app", "stop", "db"},
+		}, stepCmds(deploy.StopSteps(cfg, "/tmp/app", targets)))
+	})
+
+	t.Run("service with its own node", func(t *testing.T) {
+		targets, err := deploy.Targets(cfg, "admin")
+		require.NoError(t, err)
+		require.Equal(t,
+			[]string{"check tailnet for app-admin", "docker compose stop", "docker compose up"},
+			stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+		require.Equal(t, [][]string{
+			{"docker", "compose", "-p", "app", "stop", "admin", "ts-app-admin"},
+		}, stepCmds(deploy.StopSteps(cfg, "/tmp/app", targets)))
+	})
+
+	t.Run("unknown service", func(t *testing.T) {
+		_, err := deploy.Targets(cfg, "nope")
+		require.Error(t, err)
+	})
+}
+
+func TestExecute(t *testing.T) {
+	someErr := errors.New("some error")
+	ran := []string{}
+	step := func(name string, err error) deploy.Step {
+		return deploy.Step{Name: name, Run: func(ctx context.Context) error {
+			ran = append(ran, name)
+			return err
+		}}
+	}
+
+	err := deploy.Execute(t.Context(), []deploy.Step{step("one", nil), step("two", someErr), step("three", nil)})
+
+	var stepErr *deploy.StepError
+	require.ErrorAs(t, err, &stepErr)
+	require.Equal(t, "two", stepErr.Step)
+	require.ErrorIs(t, err, someErr)
+	require.Equal(t, []string{"one", "two"}, ran)
+}
+
+func TestCommand(t *testing.T) {
+	err := deploy.Execute(t.Context(), []deploy.Step{deploy.Command("list", t.TempDir(), nil, "sh", "-c", "echo out; echo err >&2; exit 3")})
+
+	var stepErr *deploy.StepError
+	require.ErrorAs(t, err, &stepErr)
+	require.Equal(t, "list", stepErr.Step)
+	require.Equal(t, "out\n", stepErr.Stdout)
+	require.Equal(t, "err\n", stepErr.Stderr)
+}
+
+func TestCheckEnv(t *testing.T) {
+	t.Setenv("GOTS_TEST_SET", "value")
+
+	require.NoError(t, deploy.CheckEnv("GOTS_TEST_SET").Run(t.Context()))
+	require.ErrorContains(t, deploy.CheckEnv("GOTS_TEST_SET", "GOTS_TEST_UNSET").Run(t.Context()), "GOTS_TEST_UNSET")
+}
+
+func TestGoDockerBuildSteps(t *testing.T) {
+	cfg := goConfig()
+	cfg.GoDockerBuild = Ptr(true)
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"docker", "build", "--network=host", "-f", "/tmp/app/Dockerfile", "-t", "app", "."},
+		{"docker", "compose", "-p", "app", "stop"},
+		{"docker", "compose", "-p", "app", "up", "-d"},
+	}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+}
+
+func TestTargetPlatformSteps(t *testing.T) {
+	cfg := goConfig()
+	cfg.TargetPlatform = Ptr("linux/arm64")
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"go", "build", "-o", "/tmp/app/app", "./cmd/app"},
+		{"docker", "build", "--network=host", "--platform", "linux/arm64", "-t", "app", "."},
+		{"docker", "compose", "-p", "app", "stop"},
+		{"docker", "compose", "-p", "app", "up", "-d"},
+	}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+}
+
+func TestPodmanSteps(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.Engine = Ptr("podman")
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"podman", "build", "--network=host", "-t", "app", "."},
+		{"podman", "compose", "-p", "app", "stop"},
+		{"podman", "compose", "-p", "app", "up", "-d"},
+	}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+}
+
+func TestRemoteSteps(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.DockerHost = Ptr("ssh://me@server")
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{
+		{"docker", "build", "--network=host", "-t", "app", "."},
+		{"docker", "save", "app", "|", "docker", "load"},
+		{"docker", "compose", "-p", "app", "stop"},
+		{"docker", "compose", "-p", "app", "up", "-d"},
+	}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+
+	cfg.Type = builder.AppTypeDockerImage
+	require.Equal(t,
+		[]string{"check tailnet for app", "docker compose stop", "docker compose up"},
+		stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+}
+
+func TestEphemeralSteps(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.Type = builder.AppTypeDockerImage
+	cfg.TailscaleEphemeral = Ptr(true)
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	steps := deploy.StartSteps(cfg, "/tmp/app", targets, false)
+	require.Equal(t, []string{"check for TS_AUTHKEY", "docker compose stop", "docker compose up"}, stepNames(steps))
+
+	t.Setenv("TS_AUTHKEY", "")
+	require.ErrorIs(t, steps[0].Run(t.Context()), deploy.ErrMissingAuthKey)
+	t.Setenv("TS_AUTHKEY", "tskey-auth-test")
+	require.NoError(t, steps[0].Run(t.Context()))
+}
+
+func TestTsnetBuildSteps(t *testing.T) {
+	cfg := goConfig()
+	cfg.GoTsnet = Ptr(true)
+
+	t.Run("host build", func(t *testing.T) {
+		require.Equal(t, [][]string{
+			{"go", "build", "-o", "/tmp/app/app", "./cmd/app"},
+			{"go", "build", "-trimpath", "-o", "gots-tsnet", "."},
+			{"docker", "build", "--network=host", "-t", "app", "."},
+		}, stepCmds(deploy.BuildSteps(cfg, "/tmp/app")))
+	})
+
+	t.Run("docker build", func(t *testing.T) {
+		cfg.GoDockerBuild = Ptr(true)
+		require.Equal(t, [][]string{
+			{"docker", "build", "--network=host", "-f", "/tmp/app/Dockerfile", "-t", "app",
+				"--build-context", "gots-tsnet=/tmp/app/gots-tsnet", "."},
+		}, stepCmds(deploy.BuildSteps(cfg, "/tmp/app")))
+	})
+}
+
+func TestRedeploySteps(t *testing.T) {
+	cfg := goConfig()
+
+	// Only the application's container is recreated
+	require.Equal(t, [][]string{
+		{"go", "build", "-o", "/tmp/app/app", "./cmd/app"},
+		{"docker", "build", "--network=host", "-t", "app", "."},
+		{"docker", "compose", "-p", "app", "up", "-d", "--no-deps", "--force-recreate", "app"},
+	}, stepCmds(deploy.RedeploySteps(cfg, "/tmp/app")))
+
+	cfg.Type = builder.AppTypeDockerFile
+	cfg.DockerHost = Ptr("ssh://me@server")
+	require.Equal(t, [][]string{
+		{"docker", "build", "--network=host", "-t", "app", "."},
+		{"docker", "save", "app", "|", "docker", "load"},
+		{"docker", "compose", "-p", "app", "up", "-d", "--no-deps", "--force-recreate", "app"},
+	}, stepCmds(deploy.RedeploySteps(cfg, "/tmp/app")))
+
+	// It waits for the recreated container to be ready like -start does
+	cfg.Port = Ptr(80)
+	cfg.HealthCheck = Ptr("/healthz")
+	names := stepNames(deploy.RedeploySteps(cfg, "/tmp/app"))
+	require.Equal(t, []string{"wait for app to be healthy", "wait for app to be served"}, names[len(names)-2:])
+}
+
+func TestStepTimeout(t *testing.T) {
+	step := deploy.Step{Name: "hang", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	err := deploy.Execute(t.Context(), []deploy.Step{step})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Nothing runs once the context is canceled
+	ran := false
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	err = deploy.Execute(ctx, []deploy.Step{{Name: "never", Run: func(ctx context.Context) error {
+		ran = true
+		return nil
+	}}})
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, ran)
+}
+
+func TestStartStop(t *testing.T) {
+	cfg := dockerfileConfig()
+	dir := t.TempDir()
+	fake := fakeTailnet([]string{"app"})
+	defer run.SetDefault(fake)()
+
+	require.NoError(t, deploy.Start(t.Context(), cfg, dir, "", false))
+	history, err := state.LoadHistory(dir)
+	require.NoError(t, err)
+	require.Len(t, history.Images, 1)
+	tag := history.Current
+	require.Equal(t, []string{
+		"tailscale status --json",
+		"docker build --network=host -t app .",
+		"git rev-parse --short HEAD",
+		"git status --porcelain",
+		"docker tag app app:" + tag,
+		"docker compose -p app stop",
+		"docker compose -p app up -d",
+	}, fake.Lines())
+	// Long running commands stream their output
+	build := fake.Cmds()[1]
+	require.True(t, build.Stream)
+	require.Equal(t, "docker build | ", build.Prefix)
+	require.Equal(t, "/src", build.Dir)
+
+	fake = fakeTailnet([]string{"app"})
+	defer run.SetDefault(fake)()
+	require.NoError(t, deploy.Stop(t.Context(), cfg, dir, ""))
+	require.Equal(t, []string{"docker compose -p app stop"}, fake.Lines())
+	require.Equal(t, "TS_AUTHKEY=", fake.Cmds()[0].Env[len(fake.Cmds()[0].Env)-1])
+}
+
+func TestStartFailure(t *testing.T) {
+	cfg := dockerfileConfig()
+	fake := fakeTailnet([]string{"app"}, "docker build --network=host -t app .")
+	defer run.SetDefault(fake)()
+
+	err := deploy.Start(t.Context(), cfg, t.TempDir(), "", false)
+	var stepErr *deploy.StepError
+	require.ErrorAs(t, err, &stepErr)
+	require.Equal(t, "docker build", stepErr.Step)
+	require.Equal(t, "boom\n", stepErr.Stderr)
+	// Nothing is started after the build fails
+	require.Equal(t, []string{"tailscale status --json", "docker build --network=host -t app ."}, fake.Lines())
+
+	// A new application needs TS_AUTHKEY
+	t.Setenv("TS_AUTHKEY", "")
+	fake = fakeTailnet(nil)
+	defer run.SetDefault(fake)()
+	err = deploy.Start(t.Context(), cfg, t.TempDir(), "", false)
+	require.ErrorIs(t, err, deploy.ErrMissingAuthKey)
+	require.Equal(t, []string{"tailscale status --json"}, fake.Lines())
+}
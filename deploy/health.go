@@ -0,0 +1,191 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/tailscale"
+)
+
+var (
+	// probeTimeout limits each probe
+	probeTimeout = 5 * time.Second
+	// pollInterval is the time between checks of a container's health
+	pollInterval = time.Second
+	// serveTimeout limits how long the serve endpoint has to answer. The first request can be slow as it waits for
+	// the node's certificate.
+	serveTimeout = 2 * time.Minute
+)
+
+// Probe connects to a tcp:// URL or makes a GET request to an http:// or https:// URL. HTTP errors are failures.
+func Probe(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "tcp" {
+		conn, err := net.DialTimeout("tcp", u.Host, probeTimeout)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	code, err := get(rawURL, u.Host)
+	if err != nil {
+		return err
+	}
+	if code >= 400 {
+		return fmt.Errorf("%s returned %d %s", rawURL, code, http.StatusText(code))
+	}
+	return nil
+}
+
+// get makes a GET request to rawURL by connecting to addr and returns the response's status code
+func get(rawURL string, addr string) (int, error) {
+	dialer := net.Dialer{}
+	client := http.Client{
+		Timeout: probeTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// sleep waits for d or until ctx is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WaitHealthy returns a Step that waits until the application passes its health check. The container engine runs
+// the health check for containers while systemd services are probed directly. The error includes the output of the
+// last failed check.
+func WaitHealthy(cfg *config.Config) Step {
+	app := config.Deref(cfg.DockerHostname)
+	interval := cfg.HealthCheckIntervalSafe()
+	retries := cfg.HealthCheckRetriesSafe()
+	return Step{
+		Name: "wait for " + app + " to be healthy",
+		Run: func(ctx context.Context) error {
+			if cfg.Systemd() {
+				var err error
+				for range retries {
+					if err := sleep(ctx, interval); err != nil {
+						return err
+					}
+					err = Probe(cfg.HealthCheckURL())
+					if err == nil {
+						return nil
+					}
+				}
+				return fmt.Errorf("%s is unhealthy: %w", app, err)
+			}
+
+			// The first check is run after an interval and the container is unhealthy after retries failures
+			deadline := time.Now().Add(interval * time.Duration(retries+2))
+			output := ""
+			for time.Now().Before(deadline) {
+				containers, err := getContainers(ctx, cfg)
+				if err != nil {
+					return err
+				}
+				for _, c := range containers {
+					if c.Service != cfg.NodeService(app) {
+						continue
+					}
+					output = c.HealthOutput
+					switch c.Health {
+					case "healthy":
+						return nil
+					case "unhealthy":
+						return fmt.Errorf("%s is unhealthy: %s", app, output)
+					}
+				}
+				if err := sleep(ctx, pollInterval); err != nil {
+					return err
+				}
+			}
+			return fmt.Errorf("%s didn't become healthy: %s", app, output)
+		},
+	}
+}
+
+// WaitServing returns a Step that waits until the application's node answers HTTPS requests on port. Any response
+// other than a server error means the node is serving the application.
+func WaitServing(cfg *config.Config, port int) Step {
+	app := config.Deref(cfg.DockerHostname)
+	return Step{
+		Name: "wait for " + app + " to be served",
+		Run: func(ctx context.Context) error {
+			deadline := time.Now().Add(serveTimeout)
+			var err error
+			for time.Now().Before(deadline) {
+				err = probeServe(ctx, app, port)
+				if err == nil {
+					return nil
+				}
+				if err := sleep(ctx, pollInterval); err != nil {
+					return err
+				}
+			}
+			return fmt.Errorf("%s isn't being served: %w", app, err)
+		},
+	}
+}
+
+// probeServe makes a request to the node's serve endpoint. It connects to the node's tailnet IP so it doesn't depend
+// on MagicDNS.
+func probeServe(ctx context.Context, hostname string, port int) error {
+	status, err := tailscale.GetStatus(ctx)
+	if err != nil {
+		return err
+	}
+	peer := status.FindPeer(hostname)
+	if peer == nil || len(peer.TailscaleIPs) == 0 {
+		return fmt.Errorf("%s isn't in the tailnet", hostname)
+	}
+	dnsName := strings.TrimSuffix(peer.DNSName, ".")
+	rawURL := "https://" + dnsName + ":" + strconv.Itoa(port) + "/"
+	code, err := get(rawURL, net.JoinHostPort(peer.TailscaleIPs[0], strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	// Client errors (e.g. a 404 for a path that isn't routed) still mean the application is being served
+	if code >= 500 {
+		return fmt.Errorf("%s returned %d %s", rawURL, code, http.StatusText(code))
+	}
+	return nil
+}
+
+// healthSteps returns the steps that wait for the application to be ready after it's started
+func healthSteps(cfg *config.Config) []Step {
+	if cfg.HealthCheckSafe() == "" {
+		return nil
+	}
+	steps := []Step{WaitHealthy(cfg)}
+	if listeners := cfg.WebListeners(); len(listeners) > 0 {
+		steps = append(steps, WaitServing(cfg, listeners[0].Port))
+	}
+	return steps
+}
@@ -0,0 +1,94 @@
+package deploy_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/deploy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	require.NoError(t, deploy.Probe(server.URL+"/healthz"))
+	require.EqualError(t, deploy.Probe(server.URL+"/nope"), server.URL+"/nope returned 404 Not Found")
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	require.NoError(t, deploy.Probe("tcp://"+u.Host))
+
+	// Nothing listens on a closed listener's port
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+	require.Error(t, deploy.Probe("tcp://"+l.Addr().String()))
+}
+
+func TestWaitHealthySystemd(t *testing.T) {
+	healthy := atomic.Bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	cfg := systemdConfig()
+	cfg.Port = Ptr(port)
+	cfg.HealthCheck = Ptr("/healthz")
+	cfg.HealthCheckInterval = Ptr("10ms")
+	cfg.HealthCheckRetries = Ptr(2)
+
+	err = deploy.WaitHealthy(cfg).Run(t.Context())
+	require.ErrorContains(t, err, "app is unhealthy: ")
+	require.ErrorContains(t, err, "returned 503 Service Unavailable")
+
+	healthy.Store(true)
+	require.NoError(t, deploy.WaitHealthy(cfg).Run(t.Context()))
+}
+
+func TestHealthSteps(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.Type = builder.AppTypeDockerImage
+	cfg.Port = Ptr(80)
+	cfg.HealthCheck = Ptr("/healthz")
+	cfg.Services = []config.Service{{Name: "db", Image: "postgres"}}
+
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"check tailnet for app", "docker compose stop", "docker compose up", "wait for app to be healthy", "wait for app to be served"},
+		stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+
+	// The application isn't restarted with its services
+	targets, err = deploy.Targets(cfg, "db")
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"check tailnet for app", "docker compose stop", "docker compose up"},
+		stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+
+	systemd := systemdConfig()
+	systemd.Port = Ptr(80)
+	systemd.HealthCheck = Ptr(config.HealthCheckTCP)
+	targets, err = deploy.Targets(systemd, "")
+	require.NoError(t, err)
+	steps := stepNames(deploy.StartSteps(systemd, "/tmp/app", targets, false))
+	require.Equal(t, []string{"wait for app to be healthy", "wait for app to be served"}, steps[len(steps)-2:])
+}
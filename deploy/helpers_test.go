@@ -0,0 +1,91 @@
+package deploy_test
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/run"
+	"github.com/efarrer/gots/tailscale"
+)
+
+func Ptr[T any](t T) *T {
+	return &t
+}
+
+func stepNames(steps []deploy.Step) []string {
+	names := []string{}
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	return names
+}
+
+func stepCmds(steps []deploy.Step) [][]string {
+	cmds := [][]string{}
+	for _, step := range steps {
+		if len(step.Cmd) > 0 {
+			cmds = append(cmds, step.Cmd)
+		}
+	}
+	return cmds
+}
+
+// fakeTailnet returns a fake runner that reports that the hostnames are in the tailnet and fails the command lines
+// in fail with "boom"
+func fakeTailnet(hostnames []string, fail ...string) *run.Fake {
+	return &run.Fake{Handler: func(cmd run.Cmd) (run.Result, error) {
+		if slices.Contains(fail, cmd.String()) {
+			return run.Result{Stderr: "boom\n"}, errors.New("exit status 1")
+		}
+		if cmd.String() == "tailscale status --json" {
+			status := tailscale.Status{Peer: map[string]tailscale.Peer{}}
+			for _, hostname := range hostnames {
+				status.Peer[hostname] = tailscale.Peer{HostName: hostname}
+			}
+			data, err := json.Marshal(status)
+			return run.Result{Stdout: string(data)}, err
+		}
+		return run.Result{}, nil
+	}}
+}
+
+// goConfig returns a go configuration for "app" built from ./cmd/app. The tests change the fields they're about.
+func goConfig() *config.Config {
+	return &config.Config{
+		Type:           builder.AppTypeGo,
+		DockerHostname: Ptr("app"),
+		DockerImage:    Ptr("app"),
+		ExecName:       Ptr("app"),
+		GoCompilePath:  Ptr("./cmd/app"),
+		WorkDir:        Ptr("/src"),
+	}
+}
+
+// dockerfileConfig returns a dockerfile configuration for "app". The tests change the fields they're about.
+func dockerfileConfig() *config.Config {
+	return &config.Config{
+		Type:           builder.AppTypeDockerFile,
+		DockerHostname: Ptr("app"),
+		DockerImage:    Ptr("app"),
+		WorkDir:        Ptr("/src"),
+	}
+}
+
+// systemdConfig returns a go configuration that runs as a systemd service on an arm64 host
+func systemdConfig() *config.Config {
+	cfg := goConfig()
+	cfg.Backend = Ptr(config.BackendSystemd)
+	cfg.TargetPlatform = Ptr("linux/arm64")
+	return cfg
+}
+
+// registryConfig returns a dockerfile configuration that pushes to a registry
+func registryConfig() *config.Config {
+	cfg := dockerfileConfig()
+	cfg.Registry = Ptr("localhost:5000/team")
+	return cfg
+}
@@ -0,0 +1,146 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/run"
+	"github.com/efarrer/gots/state"
+)
+
+// release is the image that is tagged when it's built and recorded in the history once it's running
+type release struct {
+	image state.Image
+}
+
+// ImageTag returns the tag for an image built from commit (which may be empty) at time t
+func ImageTag(commit string, t time.Time) string {
+	stamp := t.UTC().Format("20060102150405")
+	if commit == "" {
+		return stamp
+	}
+	return commit + "-" + stamp
+}
+
+// gitCommit returns the abbreviated commit of the source in dir with a -dirty suffix if it has uncommitted changes.
+// It's empty if dir isn't in git.
+func gitCommit(ctx context.Context, dir string) string {
+	result, err := run.Run(ctx, run.Cmd{Name: "git", Args: []string{"rev-parse", "--short", "HEAD"}, Dir: dir})
+	if err != nil {
+		return ""
+	}
+	commit := strings.TrimSpace(result.Stdout)
+	result, err = run.Run(ctx, run.Cmd{Name: "git", Args: []string{"status", "--porcelain"}, Dir: dir})
+	if err == nil && strings.TrimSpace(result.Stdout) != "" {
+		commit += "-dirty"
+	}
+	return commit
+}
+
+// tagImage returns a Step that tags the image that was just built with its commit and build time so it can be rolled
+// back to
+func tagImage(cfg *config.Config, r *release) Step {
+	image := config.Deref(cfg.DockerImage)
+	e := cfg.ContainerEngine().Local()
+	name := "tag " + image
+	return Step{
+		Name:    name,
+		Timeout: CommandTimeout,
+		Run: func(ctx context.Context) error {
+			commit := gitCommit(ctx, config.Deref(cfg.WorkDir))
+			now := time.Now()
+			r.image = state.Image{Tag: ImageTag(commit, now), Commit: commit, Time: now}
+			result, err := run.Run(ctx, run.Cmd{Name: e.Name(), Args: []string{"tag", image, cfg.ImageTagged(r.image.Tag)}, Env: e.Env()})
+			if err != nil {
+				return &StepError{Step: name, Stdout: result.Stdout, Stderr: result.Stderr, Err: err}
+			}
+			return nil
+		},
+	}
+}
+
+// recordImage returns a Step that records the released image as the current one in the history in dir. The tags of
+// the images that no longer fit in the history are removed.
+func recordImage(cfg *config.Config, dir string, r *release) Step {
+	e := cfg.ContainerEngine().Local()
+	return Step{
+		Name:    "record image history",
+		Timeout: CommandTimeout,
+		Run: func(ctx context.Context) error {
+			history, err := state.LoadHistory(dir)
+			if err != nil {
+				return err
+			}
+			dropped := history.Add(r.image)
+			err = state.SaveHistory(dir, history)
+			if err != nil {
+				return err
+			}
+			// Only the tags are removed and images that are still in use are kept, so failures don't matter
+			for _, image := range dropped {
+				run.Run(ctx, run.Cmd{Name: e.Name(), Args: []string{"rmi", cfg.ImageTagged(image.Tag)}, Env: e.Env()})
+			}
+			return nil
+		},
+	}
+}
+
+// recreateApp returns a Step that recreates only the application's container. Its sidecar is started if it isn't
+// running but otherwise left alone.
+func recreateApp(cfg *config.Config, dir string) Step {
+	e := cfg.ContainerEngine()
+	args := append(e.Compose(ComposeProject(cfg), "up"), "-d", "--no-deps", "--force-recreate", config.Deref(cfg.DockerHostname))
+	return Command(e.Name()+" compose up", dir, e.Env(), e.Name(), args...)
+}
+
+// RollbackSteps returns the steps that point the application's image back at the image in the history in dir with
+// the tag and recreate the application's container with it. Nothing is rebuilt.
+func RollbackSteps(cfg *config.Config, dir string, tag string) []Step {
+	image := config.Deref(cfg.DockerImage)
+	e := cfg.ContainerEngine()
+	steps := []Step{
+		Command("tag "+image, "", e.Local().Env(), e.Name(), "tag", cfg.ImageTagged(tag), image),
+	}
+	if e.Remote() {
+		steps = append(steps, ShipImage(cfg))
+	}
+	return append(steps,
+		recreateApp(cfg, dir),
+		Step{
+			Name: "record image history",
+			Run: func(ctx context.Context) error {
+				history, err := state.LoadHistory(dir)
+				if err != nil {
+					return err
+				}
+				history.Current = tag
+				return state.SaveHistory(dir, history)
+			},
+		},
+	)
+}
+
+// Rollback points the application whose generated files are in dir back at a previously deployed image. If tag is
+// empty the image deployed before the current one is used.
+func Rollback(ctx context.Context, cfg *config.Config, dir string, tag string) (string, error) {
+	if !cfg.BuildsImage() {
+		return "", fmt.Errorf("Only go and dockerfile applications that run in containers can be rolled back\n")
+	}
+	history, err := state.LoadHistory(dir)
+	if err != nil {
+		return "", err
+	}
+	if tag == "" {
+		previous := history.Previous()
+		if previous == nil {
+			return "", fmt.Errorf("There isn't an earlier image to roll back to\n")
+		}
+		tag = previous.Tag
+	} else if history.Find(tag) == nil {
+		return "", fmt.Errorf("Unknown image %s (gots rollback -list shows the deployed images)\n", tag)
+	}
+	return tag, Execute(ctx, RollbackSteps(cfg, dir, tag))
+}
@@ -0,0 +1,51 @@
+package deploy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageTag(t *testing.T) {
+	built := time.Date(2025, 1, 2, 3, 4, 5, 0, time.FixedZone("PST", -8*60*60))
+	require.Equal(t, "abc1234-20250102110405", deploy.ImageTag("abc1234", built))
+	require.Equal(t, "abc1234-dirty-20250102110405", deploy.ImageTag("abc1234-dirty", built))
+	require.Equal(t, "20250102110405", deploy.ImageTag("", built))
+}
+
+func TestRollbackSteps(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.DockerImage = Ptr("registry:5000/app:stable")
+	require.Equal(t, [][]string{
+		{"docker", "tag", "registry:5000/app:abc1234-20250102030405", "registry:5000/app:stable"},
+		{"docker", "compose", "-p", "app", "up", "-d", "--no-deps", "--force-recreate", "app"},
+	}, stepCmds(deploy.RollbackSteps(cfg, "/tmp/app", "abc1234-20250102030405")))
+
+	// Nothing is rebuilt for a remote engine but the image is shipped
+	cfg.DockerHost = Ptr("ssh://me@server")
+	require.Equal(t,
+		[]string{"tag registry:5000/app:stable", "ship registry:5000/app:stable", "docker compose up", "record image history"},
+		stepNames(deploy.RollbackSteps(cfg, "/tmp/app", "abc1234-20250102030405")))
+}
+
+func TestRollback(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.Type = builder.AppTypeDockerImage
+	dir := t.TempDir()
+	_, err := deploy.Rollback(t.Context(), cfg, dir, "")
+	require.ErrorContains(t, err, "can be rolled back")
+
+	cfg.Type = builder.AppTypeDockerFile
+	_, err = deploy.Rollback(t.Context(), cfg, dir, "")
+	require.ErrorContains(t, err, "There isn't an earlier image")
+
+	history := state.History{}
+	history.Add(state.Image{Tag: "one"})
+	require.NoError(t, state.SaveHistory(dir, history))
+	_, err = deploy.Rollback(t.Context(), cfg, dir, "two")
+	require.ErrorContains(t, err, "Unknown image two")
+}
@@ -0,0 +1,64 @@
+package deploy
+
+import (
+	"context"
+	"slices"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/run"
+)
+
+// LogOptions selects which logs are shown
+type LogOptions struct {
+	Follow bool
+	Since  string
+	// App shows the logs of the application and/or its services
+	App bool
+	// Sidecar shows the logs of the tailscale sidecars
+	Sidecar bool
+}
+
+// LogsArgs returns the container engine arguments that show the logs of the targets. Each line is prefixed with the name of
+// the service that logged it.
+func LogsArgs(cfg *config.Config, targets []Target, opts LogOptions) []string {
+	args := cfg.ContainerEngine().Compose(ComposeProject(cfg), "logs")
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Since != "" {
+		args = append(args, "--since", opts.Since)
+	}
+	if opts.App {
+		for _, t := range targets {
+			args = append(args, t.Service)
+		}
+	}
+	if opts.Sidecar {
+		var services []string
+		if opts.App {
+			services = args[len(args)-len(targets):]
+		}
+		for _, node := range nodes(targets) {
+			// In tsnet mode the application's node runs in the application's container
+			if service := cfg.NodeService(node); !slices.Contains(services, service) {
+				args = append(args, service)
+			}
+		}
+	}
+	return args
+}
+
+// Logs streams the logs of the targets to stdout until they end or ctx is canceled
+func Logs(ctx context.Context, cfg *config.Config, targets []Target, opts LogOptions) error {
+	cmd := run.Cmd{Name: "journalctl", Args: JournalArgs(cfg, opts), Stream: true}
+	if !cfg.Systemd() {
+		e := cfg.ContainerEngine()
+		cmd = run.Cmd{Name: e.Name(), Args: LogsArgs(cfg, targets, opts), Env: e.Env(), Stream: true}
+	}
+	_, err := run.Run(ctx, cmd)
+	// Following the logs is stopped with Ctrl-C
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
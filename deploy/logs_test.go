@@ -0,0 +1,64 @@
+package deploy_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/deploy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogsArgs(t *testing.T) {
+	cfg := &config.Config{DockerHostname: Ptr("app")}
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	require.Equal(t,
+		[]string{"compose", "-p", "app", "logs", "app"},
+		deploy.LogsArgs(cfg, targets, deploy.LogOptions{App: true}))
+	require.Equal(t,
+		[]string{"compose", "-p", "app", "logs", "--follow", "--since", "10m", "app", "ts-app"},
+		deploy.LogsArgs(cfg, targets, deploy.LogOptions{Follow: true, Since: "10m", App: true, Sidecar: true}))
+	require.Equal(t,
+		[]string{"compose", "-p", "app", "logs", "ts-app"},
+		deploy.LogsArgs(cfg, targets, deploy.LogOptions{Sidecar: true}))
+}
+
+func TestLogsArgsServices(t *testing.T) {
+	cfg := dockerfileConfig()
+	cfg.Services = []config.Service{
+		{Name: "db", Image: "postgres"},
+		{Name: "admin", Image: "adminer", Hostname: "app-admin"},
+	}
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	require.Equal(t,
+		[]string{"compose", "-p", "app", "logs", "app", "db", "admin", "ts-app", "ts-app-admin"},
+		deploy.LogsArgs(cfg, targets, deploy.LogOptions{App: true, Sidecar: true}))
+
+	targets, err = deploy.Targets(cfg, "db")
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"compose", "-p", "app", "logs", "db", "ts-app"},
+		deploy.LogsArgs(cfg, targets, deploy.LogOptions{App: true, Sidecar: true}))
+}
+
+func TestLogsArgsTsnet(t *testing.T) {
+	cfg := goConfig()
+	cfg.GoTsnet = Ptr(true)
+	cfg.Services = []config.Service{{Name: "db", Image: "postgres"}}
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	// The application's node runs in the application's container
+	require.Equal(t,
+		[]string{"compose", "-p", "app", "logs", "app", "db"},
+		deploy.LogsArgs(cfg, targets, deploy.LogOptions{App: true, Sidecar: true}))
+
+	targets, err = deploy.Targets(cfg, "db")
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"compose", "-p", "app", "logs", "db", "app"},
+		deploy.LogsArgs(cfg, targets, deploy.LogOptions{App: true, Sidecar: true}))
+}
@@ -0,0 +1,75 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/efarrer/gots/config"
+)
+
+// PushSteps returns the steps that tag the application's local image with its name in the registry and push it. If tag
+// isn't empty the image with that tag (see ImageTag) is also pushed so it can be rolled back to.
+func PushSteps(cfg *config.Config, tag string) []Step {
+	e := cfg.ContainerEngine().Local()
+	image := config.Deref(cfg.DockerImage)
+	pushed := cfg.RegistryImage()
+	steps := []Step{
+		Command("tag "+pushed, "", e.Env(), e.Name(), "tag", image, pushed),
+		transfer(Command("push "+pushed, "", e.Env(), e.Name(), "push", pushed)),
+	}
+	if tag != "" {
+		tagged := cfg.RegistryImageTagged(tag)
+		steps = append(steps,
+			Command("tag "+tagged, "", e.Env(), e.Name(), "tag", cfg.ImageTagged(tag), tagged),
+			transfer(Command("push "+tagged, "", e.Env(), e.Name(), "push", tagged)),
+		)
+	}
+	return steps
+}
+
+// checkPush returns an error if the application's image can't be pushed
+func checkPush(cfg *config.Config) error {
+	if !cfg.BuildsImage() {
+		return fmt.Errorf("Only the images of go and dockerfile applications that run in containers can be pushed\n")
+	}
+	if config.Deref(cfg.Registry) == "" {
+		return fmt.Errorf("There isn't a registry to push to, re-run gots with -config -reset Registry\n")
+	}
+	return nil
+}
+
+// Push pushes the application's image, and the image with the tag if it isn't empty, to its registry
+func Push(ctx context.Context, cfg *config.Config, tag string) error {
+	err := checkPush(cfg)
+	if err != nil {
+		return err
+	}
+	return Execute(ctx, PushSteps(cfg, tag))
+}
+
+// pushRelease returns a Step that pushes the image that was just built and tagged
+func pushRelease(cfg *config.Config, r *release) Step {
+	return Step{
+		Name: "push " + cfg.RegistryImage(),
+		Run: func(ctx context.Context) error {
+			return Execute(ctx, PushSteps(cfg, r.image.Tag))
+		},
+	}
+}
+
+// transfer gives a step that pushes or pulls an image as long as a build
+func transfer(step Step) Step {
+	step.Timeout = BuildTimeout
+	return step
+}
+
+// PullSteps returns the steps that pull the application's image from its registry to the (remote) container engine
+// and give it the name used by the compose file
+func PullSteps(cfg *config.Config) []Step {
+	e := cfg.ContainerEngine()
+	pulled := cfg.RegistryImage()
+	return []Step{
+		transfer(Command("pull "+pulled, "", e.Env(), e.Name(), "pull", pulled)),
+		Command("tag "+config.Deref(cfg.DockerImage), "", e.Env(), e.Name(), "tag", pulled, config.Deref(cfg.DockerImage)),
+	}
+}
@@ -0,0 +1,87 @@
+package deploy_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/efarrer/gots/config/builder"
+	"github.com/efarrer/gots/deploy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushSteps(t *testing.T) {
+	cfg := registryConfig()
+	require.Equal(t, [][]string{
+		{"docker", "tag", "app", "localhost:5000/team/app"},
+		{"docker", "push", "localhost:5000/team/app"},
+		{"docker", "tag", "app:abc1234-20250102030405", "localhost:5000/team/app:abc1234-20250102030405"},
+		{"docker", "push", "localhost:5000/team/app:abc1234-20250102030405"},
+	}, stepCmds(deploy.PushSteps(cfg, "abc1234-20250102030405")))
+
+	// Without a tag only the image is pushed
+	require.Len(t, deploy.PushSteps(cfg, ""), 2)
+}
+
+func TestPushStartSteps(t *testing.T) {
+	cfg := registryConfig()
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"check tailnet for app", "docker build", "tag app", "push localhost:5000/team/app", "docker compose stop", "docker compose up", "record image history"},
+		stepNames(deploy.StartSteps(cfg, "/tmp/app", targets, true)))
+
+	// A remote engine pulls the pushed image instead of it being shipped
+	cfg.DockerHost = Ptr("ssh://me@server")
+	require.Equal(t, [][]string{
+		{"docker", "build", "--network=host", "-t", "app", "."},
+		{"docker", "pull", "localhost:5000/team/app"},
+		{"docker", "tag", "localhost:5000/team/app", "app"},
+		{"docker", "compose", "-p", "app", "stop"},
+		{"docker", "compose", "-p", "app", "up", "-d"},
+	}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, true)))
+}
+
+func TestPushErrors(t *testing.T) {
+	cfg := registryConfig()
+	cfg.Registry = nil
+	require.ErrorContains(t, deploy.Push(t.Context(), cfg, ""), "There isn't a registry")
+	require.ErrorContains(t, deploy.Start(t.Context(), cfg, "/tmp/app", "", true), "There isn't a registry")
+
+	cfg.Type = builder.AppTypeDockerImage
+	require.ErrorContains(t, deploy.Push(t.Context(), cfg, ""), "can be pushed")
+}
+
+// TestPushRegistry pushes an image to the registry in GOTS_TEST_REGISTRY, e.g. one started with
+// `docker run -d -p 5000:5000 registry:2` and GOTS_TEST_REGISTRY=localhost:5000
+func TestPushRegistry(t *testing.T) {
+	registry := os.Getenv("GOTS_TEST_REGISTRY")
+	if registry == "" {
+		t.Skip("GOTS_TEST_REGISTRY isn't set")
+	}
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Skip("docker isn't installed")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch\nCOPY Dockerfile /\n"), 0644))
+	cfg := registryConfig()
+	cfg.DockerHostname = Ptr("gots-push-test")
+	cfg.DockerImage = Ptr("gots-push-test")
+	cfg.WorkDir = Ptr(dir)
+	cfg.Registry = Ptr(registry)
+	tag := "test"
+	require.NoError(t, deploy.Execute(t.Context(), deploy.BuildSteps(cfg, dir)))
+	require.NoError(t, exec.Command("docker", "tag", "gots-push-test", cfg.ImageTagged(tag)).Run())
+	require.NoError(t, deploy.Push(t.Context(), cfg, tag))
+
+	resp, err := http.Get("http://" + registry + "/v2/gots-push-test/tags/list")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	tags := struct{ Tags []string }{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&tags))
+	require.ElementsMatch(t, []string{"latest", tag}, tags.Tags)
+}
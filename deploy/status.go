@@ -0,0 +1,192 @@
+package deploy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/run"
+	"github.com/efarrer/gots/tailscale"
+)
+
+// ContainerStatus is the state of one of the application's containers
+type ContainerStatus struct {
+	Name         string
+	Service      string
+	State        string
+	Running      bool
+	RestartCount int
+	// Health is the result of the container's healthcheck (starting, healthy, or unhealthy). Empty if it doesn't
+	// have one.
+	Health string `json:",omitempty"`
+	// HealthOutput is the output of the last healthcheck
+	HealthOutput string `json:",omitempty"`
+}
+
+// NodeStatus is the state of one of the application's tailnet nodes
+type NodeStatus struct {
+	Hostname     string
+	InTailnet    bool
+	Online       bool
+	DNSName      string
+	TailscaleIPs []string
+	Funnel       bool
+}
+
+// Status is the state of the application's containers and tailnet nodes
+type Status struct {
+	Containers []ContainerStatus
+	Nodes      []NodeStatus
+}
+
+var invalidProjectChars = regexp.MustCompile("[^a-z0-9_-]")
+
+// ComposeProject returns the compose project name used for the application
+func ComposeProject(cfg *config.Config) string {
+	return invalidProjectChars.ReplaceAllString(strings.ToLower(config.Deref(cfg.DockerHostname)), "")
+}
+
+// ParseContainers parses the output of `docker inspect` (or `podman inspect`) for the application's containers
+func ParseContainers(data []byte) ([]ContainerStatus, error) {
+	inspected := []struct {
+		Name         string
+		RestartCount int
+		State        struct {
+			Status  string
+			Running bool
+			Health  *struct {
+				Status string
+				Log    []struct{ Output string }
+			}
+		}
+		Config struct {
+			Labels map[string]string
+		}
+	}{}
+	err := json.Unmarshal(data, &inspected)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse docker inspect output %w", err)
+	}
+
+	containers := []ContainerStatus{}
+	for _, c := range inspected {
+		container := ContainerStatus{
+			Name:         strings.TrimPrefix(c.Name, "/"),
+			Service:      c.Config.Labels["com.docker.compose.service"],
+			State:        c.State.Status,
+			Running:      c.State.Running,
+			RestartCount: c.RestartCount,
+		}
+		if health := c.State.Health; health != nil {
+			container.Health = health.Status
+			if len(health.Log) > 0 {
+				container.HealthOutput = strings.TrimSpace(health.Log[len(health.Log)-1].Output)
+			}
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// ParseFunnel parses the output of `tailscale serve status --json` and returns true if funnel is enabled
+func ParseFunnel(data []byte) (bool, error) {
+	serve := struct {
+		AllowFunnel map[string]bool
+	}{}
+	err := json.Unmarshal(data, &serve)
+	if err != nil {
+		return false, fmt.Errorf("Unable to parse tailscale serve status %w", err)
+	}
+	for _, allowed := range serve.AllowFunnel {
+		if allowed {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getContainers returns the status of the containers in the application's docker compose project
+func getContainers(ctx context.Context, cfg *config.Config) ([]ContainerStatus, error) {
+	e := cfg.ContainerEngine()
+	result, err := run.Run(ctx, run.Cmd{Name: e.Name(), Env: e.Env(), Args: []string{"ps", "--all", "--quiet",
+		"--filter", "label=com.docker.compose.project=" + ComposeProject(cfg)}})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list containers %w\n%s", err, result.Stderr)
+	}
+	ids := strings.Fields(result.Stdout)
+	if len(ids) == 0 {
+		return []ContainerStatus{}, nil
+	}
+
+	result, err = run.Run(ctx, run.Cmd{Name: e.Name(), Env: e.Env(), Args: append([]string{"inspect"}, ids...)})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to inspect containers %w\n%s", err, result.Stderr)
+	}
+	return ParseContainers([]byte(result.Stdout))
+}
+
+// GetStatus returns the status of the containers and tailnet nodes of the targets
+func GetStatus(ctx context.Context, cfg *config.Config, targets []Target) (*Status, error) {
+	status := Status{Containers: []ContainerStatus{}, Nodes: []NodeStatus{}}
+
+	getter := getContainers
+	if cfg.Systemd() {
+		getter = getUnits
+	}
+	containers, err := getter(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	services := map[string]bool{}
+	for _, t := range targets {
+		services[t.Service] = true
+		services[t.Sidecar()] = true
+	}
+	for _, c := range containers {
+		if services[c.Service] || cfg.Systemd() {
+			status.Containers = append(status.Containers, c)
+		}
+	}
+
+	tsStatus, err := tailscale.GetStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, hostname := range nodes(targets) {
+		node := NodeStatus{Hostname: hostname}
+		if peer := tsStatus.FindPeer(hostname); peer != nil {
+			node.InTailnet = true
+			node.Online = peer.Online
+			node.DNSName = peer.DNSName
+			node.TailscaleIPs = peer.TailscaleIPs
+		}
+
+		// Without a sidecar there's no tailscale CLI to ask so the funnel state comes from the configuration
+		if cfg.Systemd() || cfg.NodeService(hostname) != "ts-"+hostname {
+			node.Funnel = slices.ContainsFunc(cfg.Listeners(), func(l config.Listener) bool { return l.Funnel })
+		}
+
+		// The funnel state is only known by the sidecar
+		for _, c := range status.Containers {
+			if c.Service != "ts-"+hostname || !c.Running {
+				continue
+			}
+			e := cfg.ContainerEngine()
+			result, err := run.Run(ctx, run.Cmd{Name: e.Name(), Env: e.Env(), Args: []string{"exec", c.Name, "tailscale", "serve", "status", "--json"}})
+			if err != nil {
+				return nil, fmt.Errorf("Unable to get serve status from %s %w\n%s", c.Name, err, result.Stderr)
+			}
+			node.Funnel, err = ParseFunnel([]byte(result.Stdout))
+			if err != nil {
+				return nil, err
+			}
+		}
+		status.Nodes = append(status.Nodes, node)
+	}
+
+	return &status, nil
+}
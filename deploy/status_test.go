@@ -0,0 +1,68 @@
+package deploy_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/deploy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeProject(t *testing.T) {
+	require.Equal(t, "my-app_1", deploy.ComposeProject(&config.Config{DockerHostname: Ptr("My-App_1.")}))
+}
+
+func TestParseContainers(t *testing.T) {
+	containers, err := deploy.ParseContainers([]byte(`[
+  {
+    "Name": "/app-ts-app-1",
+    "RestartCount": 0,
+    "State": {"Status": "running", "Running": true},
+    "Config": {"Labels": {"com.docker.compose.service": "ts-app"}}
+  },
+  {
+    "Name": "/app-app-1",
+    "RestartCount": 3,
+    "State": {"Status": "restarting", "Running": false},
+    "Config": {"Labels": {"com.docker.compose.service": "app"}}
+  }
+]`))
+	require.NoError(t, err)
+	require.Equal(t, []deploy.ContainerStatus{
+		{Name: "app-ts-app-1", Service: "ts-app", State: "running", Running: true, RestartCount: 0},
+		{Name: "app-app-1", Service: "app", State: "restarting", Running: false, RestartCount: 3},
+	}, containers)
+
+	containers, err = deploy.ParseContainers([]byte(`[
+  {
+    "Name": "/app-ts-app-1",
+    "State": {"Status": "running", "Running": true, "Health": {"Status": "unhealthy", "Log": [
+      {"Output": "Connecting to 127.0.0.1:80\n"},
+      {"Output": "wget: can't connect to remote host: Connection refused\n"}
+    ]}},
+    "Config": {"Labels": {"com.docker.compose.service": "ts-app"}}
+  }
+]`))
+	require.NoError(t, err)
+	require.Equal(t, []deploy.ContainerStatus{{
+		Name: "app-ts-app-1", Service: "ts-app", State: "running", Running: true,
+		Health: "unhealthy", HealthOutput: "wget: can't connect to remote host: Connection refused",
+	}}, containers)
+
+	_, err = deploy.ParseContainers([]byte("nope"))
+	require.Error(t, err)
+}
+
+func TestParseFunnel(t *testing.T) {
+	funnel, err := deploy.ParseFunnel([]byte(`{"AllowFunnel": {"app.tail1234.ts.net:443": true}}`))
+	require.NoError(t, err)
+	require.True(t, funnel)
+
+	funnel, err = deploy.ParseFunnel([]byte(`{"AllowFunnel": {"app.tail1234.ts.net:443": false}}`))
+	require.NoError(t, err)
+	require.False(t, funnel)
+
+	funnel, err = deploy.ParseFunnel([]byte(`{}`))
+	require.NoError(t, err)
+	require.False(t, funnel)
+}
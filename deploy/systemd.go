@@ -0,0 +1,178 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/run"
+)
+
+// systemctl returns a Step that runs systemctl on the user's service manager
+func systemctl(args ...string) Step {
+	return Command("systemctl "+args[0], "", nil, "systemctl", append([]string{"--user"}, args...)...)
+}
+
+// envFileLine formats a variable for a systemd EnvironmentFile
+func envFileLine(name string, value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	return name + `="` + value + `"` + "\n"
+}
+
+// WriteEnvironment returns a Step that writes the environment files read by the systemd units in dir. The
+// application's environment variables are resolved from the host environment and TS_AUTHKEY is saved (if it's set)
+// so the units can be restarted without gots. They're only readable by the user as they may contain secrets.
+func WriteEnvironment(cfg *config.Config, dir string) Step {
+	return Step{
+		Name: "write environment",
+		Run: func(ctx context.Context) error {
+			files := map[string]string{"environment": "", "tailscale.env": ""}
+			for _, e := range cfg.ResolvedEnv() {
+				name, value, _ := strings.Cut(e, "=")
+				files["environment"] += envFileLine(name, value)
+			}
+			if authKey := os.Getenv("TS_AUTHKEY"); authKey != "" {
+				files["tailscale.env"] = envFileLine("TS_AUTHKEY", authKey)
+			} else if data, err := os.ReadFile(filepath.Join(dir, "tailscale.env")); err == nil {
+				// The saved key is kept
+				files["tailscale.env"] = string(data)
+			}
+			for name, content := range files {
+				err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600)
+				if err != nil {
+					return fmt.Errorf("Unable to write %s %w", name, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// systemdStartSteps returns the steps that build the executable and start its systemd units whose files are in dir
+func systemdStartSteps(cfg *config.Config, dir string, targets []Target) []Step {
+	steps := []Step{CheckAuthKey(nodes(targets)...)}
+	if cfg.TailscaleEphemeralSafe() {
+		steps = []Step{RequireAuthKey()}
+	}
+	if names := cfg.EnvReferences(); len(names) > 0 {
+		steps = append(steps, CheckEnv(names...))
+	}
+	steps = append(steps, BuildSteps(cfg, dir)...)
+	steps = append(steps, WriteEnvironment(cfg, dir))
+
+	// The units are linked from dir so they're found by name
+	link := []string{"link"}
+	for _, unit := range cfg.Units() {
+		link = append(link, filepath.Join(dir, unit))
+	}
+	// Enabling the units starts them at boot (or login) through their WantedBy=default.target
+	steps = append(steps,
+		systemctl(link...),
+		systemctl("daemon-reload"),
+		systemctl(append([]string{"enable"}, cfg.Units()...)...),
+		systemctl(append([]string{"restart"}, cfg.Units()...)...),
+		checkLinger(cfg),
+	)
+	return append(steps, healthSteps(cfg)...)
+}
+
+// checkLinger returns a Step that warns when lingering isn't enabled for the user, as then the units only run while
+// they're logged in
+func checkLinger(cfg *config.Config) Step {
+	return Step{
+		Name:    "check lingering",
+		Timeout: CommandTimeout,
+		Run: func(ctx context.Context) error {
+			u, err := user.Current()
+			if err != nil {
+				return nil
+			}
+			result, err := run.Run(ctx, run.Cmd{Name: "loginctl", Args: []string{"show-user", u.Username, "--property=Linger", "--value"}})
+			// Only a definite no is reported as loginctl may not be able to answer (e.g. in a container)
+			if err == nil && strings.TrimSpace(result.Stdout) == "no" {
+				fmt.Fprintf(os.Stderr, "Lingering isn't enabled so %s only runs while %s is logged in, run loginctl enable-linger to keep it running\n",
+					cfg.Unit(), u.Username)
+			}
+			return nil
+		},
+	}
+}
+
+// systemdStopSteps returns the steps that stop the application's systemd units. They're disabled too so they stay
+// stopped after a reboot, like containers with the default unless-stopped restart policy.
+func systemdStopSteps(cfg *config.Config) []Step {
+	units := slices.Clone(cfg.Units())
+	slices.Reverse(units)
+	return []Step{
+		systemctl(append([]string{"stop"}, units...)...),
+		systemctl(append([]string{"disable"}, units...)...),
+	}
+}
+
+// JournalArgs returns the journalctl arguments that show the logs of the application's systemd units
+func JournalArgs(cfg *config.Config, opts LogOptions) []string {
+	args := []string{"--user", "--output=short-iso"}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Since != "" {
+		// journalctl doesn't understand Go durations (e.g. 10m) but does understand relative times in seconds
+		since := opts.Since
+		if d, err := time.ParseDuration(since); err == nil {
+			since = "-" + strconv.Itoa(int(d.Seconds())) + "s"
+		}
+		args = append(args, "--since", since)
+	}
+	// In tsnet mode the launcher logs to the application's unit
+	if opts.App || (opts.Sidecar && cfg.Tsnet()) {
+		args = append(args, "--unit", cfg.Unit())
+	}
+	if opts.Sidecar && !cfg.Tsnet() {
+		args = append(args, "--unit", cfg.TailscaledUnit())
+	}
+	return args
+}
+
+// ParseUnits parses the output of `systemctl show -p Id,ActiveState,SubState,NRestarts` for the application's units
+func ParseUnits(data []byte) []ContainerStatus {
+	units := []ContainerStatus{}
+	for _, block := range strings.Split(strings.TrimSpace(string(data)), "\n\n") {
+		unit := ContainerStatus{}
+		for _, line := range strings.Split(block, "\n") {
+			key, value, _ := strings.Cut(line, "=")
+			switch key {
+			case "Id":
+				unit.Name = value
+				unit.Service = value
+			case "ActiveState":
+				unit.Running = value == "active"
+			case "SubState":
+				unit.State = value
+			case "NRestarts":
+				unit.RestartCount, _ = strconv.Atoi(value)
+			}
+		}
+		if unit.Name != "" {
+			units = append(units, unit)
+		}
+	}
+	return units
+}
+
+// getUnits returns the status of the application's systemd units
+func getUnits(ctx context.Context, cfg *config.Config) ([]ContainerStatus, error) {
+	args := append([]string{"--user", "show", "-p", "Id,ActiveState,SubState,NRestarts"}, cfg.Units()...)
+	result, err := run.Run(ctx, run.Cmd{Name: "systemctl", Args: args})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get the status of the systemd units %w\n%s", err, result.Stderr)
+	}
+	return ParseUnits([]byte(result.Stdout)), nil
+}
@@ -0,0 +1,126 @@
+package deploy_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/efarrer/gots/config"
+	"github.com/efarrer/gots/deploy"
+	"github.com/efarrer/gots/run"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystemdSteps(t *testing.T) {
+	cfg := systemdConfig()
+	targets, err := deploy.Targets(cfg, "")
+	require.NoError(t, err)
+
+	// The executable is built for this host
+	require.Equal(t, [][]string{
+		{"go", "build", "-o", "/tmp/app/app", "./cmd/app"},
+		{"systemctl", "--user", "link", "/tmp/app/gots-app-tailscaled.service", "/tmp/app/gots-app.service"},
+		{"systemctl", "--user", "daemon-reload"},
+		{"systemctl", "--user", "enable", "gots-app-tailscaled.service", "gots-app.service"},
+		{"systemctl", "--user", "restart", "gots-app-tailscaled.service", "gots-app.service"},
+	}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+	require.Equal(t, [][]string{
+		{"systemctl", "--user", "stop", "gots-app.service", "gots-app-tailscaled.service"},
+		{"systemctl", "--user", "disable", "gots-app.service", "gots-app-tailscaled.service"},
+	}, stepCmds(deploy.StopSteps(cfg, "/tmp/app", targets)))
+
+	cfg.GoTsnet = Ptr(true)
+	require.Equal(t, [][]string{
+		{"go", "build", "-o", "/tmp/app/app", "./cmd/app"},
+		{"go", "build", "-trimpath", "-o", "gots-tsnet", "."},
+		{"systemctl", "--user", "link", "/tmp/app/gots-app.service"},
+		{"systemctl", "--user", "daemon-reload"},
+		{"systemctl", "--user", "enable", "gots-app.service"},
+		{"systemctl", "--user", "restart", "gots-app.service"},
+	}, stepCmds(deploy.StartSteps(cfg, "/tmp/app", targets, false)))
+}
+
+func TestWriteEnvironment(t *testing.T) {
+	cfg := systemdConfig()
+	cfg.Env = []config.EnvVar{{Name: "GREETING", Value: `say "${GOTS_TEST_NAME}"`}}
+	dir := t.TempDir()
+	t.Setenv("GOTS_TEST_NAME", "hi")
+	t.Setenv("TS_AUTHKEY", "tskey-auth-test")
+
+	require.NoError(t, deploy.WriteEnvironment(cfg, dir).Run(t.Context()))
+	environment, err := os.ReadFile(filepath.Join(dir, "environment"))
+	require.NoError(t, err)
+	require.Equal(t, `GREETING="say \"hi\""`+"\n", string(environment))
+
+	// The saved auth key is kept when TS_AUTHKEY isn't set
+	t.Setenv("TS_AUTHKEY", "")
+	require.NoError(t, deploy.WriteEnvironment(cfg, dir).Run(t.Context()))
+	tailscaleEnv, err := os.ReadFile(filepath.Join(dir, "tailscale.env"))
+	require.NoError(t, err)
+	require.Equal(t, `TS_AUTHKEY="tskey-auth-test"`+"\n", string(tailscaleEnv))
+	info, err := os.Stat(filepath.Join(dir, "tailscale.env"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestJournalArgs(t *testing.T) {
+	cfg := systemdConfig()
+
+	require.Equal(t,
+		[]string{"--user", "--output=short-iso", "--follow", "--since", "-600s", "--unit", "gots-app.service"},
+		deploy.JournalArgs(cfg, deploy.LogOptions{Follow: true, Since: "10m", App: true}))
+	require.Equal(t,
+		[]string{"--user", "--output=short-iso", "--unit", "gots-app-tailscaled.service"},
+		deploy.JournalArgs(cfg, deploy.LogOptions{Sidecar: true}))
+
+	cfg.GoTsnet = Ptr(true)
+	require.Equal(t,
+		[]string{"--user", "--output=short-iso", "--unit", "gots-app.service"},
+		deploy.JournalArgs(cfg, deploy.LogOptions{Sidecar: true}))
+}
+
+func TestParseUnits(t *testing.T) {
+	data := "Id=gots-app-tailscaled.service\nActiveState=active\nSubState=running\nNRestarts=0\n\n" +
+		"Id=gots-app.service\nActiveState=failed\nSubState=failed\nNRestarts=3\n"
+
+	require.Equal(t, []deploy.ContainerStatus{
+		{Name: "gots-app-tailscaled.service", Service: "gots-app-tailscaled.service", State: "running", Running: true},
+		{Name: "gots-app.service", Service: "gots-app.service", State: "failed", RestartCount: 3},
+	}, deploy.ParseUnits([]byte(data)))
+}
+
+func TestSystemdRedeploySteps(t *testing.T) {
+	// tailscaled isn't restarted
+	require.Equal(t, [][]string{
+		{"go", "build", "-o", "/tmp/app/app", "./cmd/app"},
+		{"systemctl", "--user", "restart", "gots-app.service"},
+	}, stepCmds(deploy.RedeploySteps(systemdConfig(), "/tmp/app")))
+
+	cfg := systemdConfig()
+	cfg.Port = Ptr(80)
+	cfg.HealthCheck = Ptr(config.HealthCheckTCP)
+	names := stepNames(deploy.RedeploySteps(cfg, "/tmp/app"))
+	require.Equal(t, "wait for app to be healthy", names[2])
+}
+
+func TestSystemdStartStop(t *testing.T) {
+	cfg := systemdConfig()
+	cfg.GoTsnet = Ptr(true)
+	dir := t.TempDir()
+	fake := fakeTailnet([]string{"app"})
+	defer run.SetDefault(fake)()
+
+	require.NoError(t, deploy.Start(t.Context(), cfg, dir, "", false))
+	lines := fake.Lines()
+	// Lingering is checked once the units are running
+	require.Equal(t, []string{
+		"systemctl --user enable gots-app.service",
+		"systemctl --user restart gots-app.service",
+	}, lines[len(lines)-3:len(lines)-1])
+	require.Regexp(t, `^loginctl show-user \S+ --property=Linger --value$`, lines[len(lines)-1])
+
+	fake = fakeTailnet([]string{"app"})
+	defer run.SetDefault(fake)()
+	require.NoError(t, deploy.Stop(t.Context(), cfg, dir, ""))
+	require.Equal(t, []string{"systemctl --user stop gots-app.service", "systemctl --user disable gots-app.service"}, fake.Lines())
+}
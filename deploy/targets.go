@@ -0,0 +1,58 @@
+package deploy
+
+import (
+	"fmt"
+
+	"github.com/efarrer/gots/config"
+)
+
+// Target is a docker compose service along with the tailnet node that it runs on
+type Target struct {
+	Service string
+	Node    string
+	// OwnNode is true if the node's sidecar only exists for this service
+	OwnNode bool
+}
+
+// Sidecar returns the name of the docker compose service for the target's tailscale sidecar
+func (t Target) Sidecar() string {
+	return "ts-" + t.Node
+}
+
+// Targets returns the application and all of its services. If name isn't empty only the application or service with
+// that name is returned.
+func Targets(cfg *config.Config, name string) ([]Target, error) {
+	app := config.Deref(cfg.DockerHostname)
+	targets := []Target{{Service: app, Node: app}}
+	for _, svc := range cfg.Services {
+		targets = append(targets, Target{Service: svc.Name, Node: cfg.ServiceNode(svc), OwnNode: svc.Hostname != ""})
+	}
+	if name == "" {
+		return targets, nil
+	}
+
+	for _, t := range targets {
+		if t.Service == name {
+			return []Target{t}, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown service %s\n", name)
+}
+
+// nodes returns the unique tailnet nodes of the targets
+func nodes(targets []Target) []string {
+	ret := []string{}
+	seen := map[string]bool{}
+	for _, t := range targets {
+		if !seen[t.Node] {
+			seen[t.Node] = true
+			ret = append(ret, t.Node)
+		}
+	}
+	return ret
+}
+
+// isAll returns true if the targets are the application and all of its services
+func isAll(cfg *config.Config, targets []Target) bool {
+	return len(targets) == len(cfg.Services)+1
+}
@@ -0,0 +1,103 @@
+package engine
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+const (
+	Docker = "docker"
+	Podman = "podman"
+)
+
+// Engine is the container engine (docker or podman) used to build and run the application
+type Engine struct {
+	name string
+	// host is a context/connection name or a URL (e.g. ssh://user@server) for a remote engine. Empty for the local
+	// engine.
+	host string
+}
+
+// New returns the engine with the given name. An empty name is docker.
+func New(name string) Engine {
+	if name == "" {
+		name = Docker
+	}
+	return Engine{name: name}
+}
+
+// WithHost returns the engine on host, which is either a docker context (or podman connection) name or a URL (e.g.
+// ssh://user@server). An empty host is the local engine.
+func (e Engine) WithHost(host string) Engine {
+	e.host = host
+	return e
+}
+
+// Local returns the engine on this machine
+func (e Engine) Local() Engine {
+	return Engine{name: e.name}
+}
+
+// Remote returns true if the engine isn't on this machine
+func (e Engine) Remote() bool {
+	return e.host != ""
+}
+
+// Env returns the environment variables that point the engine's commands at its host
+func (e Engine) Env() []string {
+	if e.host == "" {
+		return nil
+	}
+	url := strings.Contains(e.host, "://")
+	switch {
+	case e.name == Podman && url:
+		return []string{"CONTAINER_HOST=" + e.host}
+	case e.name == Podman:
+		return []string{"CONTAINER_CONNECTION=" + e.host}
+	case url:
+		return []string{"DOCKER_HOST=" + e.host}
+	default:
+		return []string{"DOCKER_CONTEXT=" + e.host}
+	}
+}
+
+// Detect returns the name of the installed engine preferring docker over podman
+func Detect() (string, error) {
+	for _, name := range []string{Docker, Podman} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, nil
+		}
+	}
+	return "", errors.New("Neither docker nor podman are installed")
+}
+
+// Name returns the engine's executable
+func (e Engine) Name() string {
+	return e.name
+}
+
+// Compose returns the engine arguments that run a compose command in the project
+func (e Engine) Compose(project string, args ...string) []string {
+	return append([]string{"compose", "-p", project}, args...)
+}
+
+// PlatformInfoArgs returns the engine arguments that print the engine's OS and architecture separated by a space
+func (e Engine) PlatformInfoArgs() []string {
+	if e.name == Podman {
+		return []string{"info", "--format", "{{.Host.OS}} {{.Host.Arch}}"}
+	}
+	return []string{"info", "--format", "{{.OSType}} {{.Architecture}}"}
+}
+
+// RootlessInfoArgs returns the engine arguments that print true if the engine runs without root. Only podman
+// reports it.
+func (e Engine) RootlessInfoArgs() []string {
+	return []string{"info", "--format", "{{.Host.Security.Rootless}}"}
+}
+
+// UserspaceNetworking returns true if the tailscale sidecar can't use /dev/net/tun and must use userspace networking
+// instead, which is the case for rootless podman
+func (e Engine) UserspaceNetworking(rootless bool) bool {
+	return e.name == Podman && rootless
+}
@@ -0,0 +1,34 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/engine"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	require.Equal(t, engine.Docker, engine.New("").Name())
+	require.Equal(t, engine.Podman, engine.New(engine.Podman).Name())
+}
+
+func TestCompose(t *testing.T) {
+	require.Equal(t, []string{"compose", "-p", "app", "up", "-d"}, engine.New(engine.Podman).Compose("app", "up", "-d"))
+}
+
+func TestUserspaceNetworking(t *testing.T) {
+	require.False(t, engine.New(engine.Docker).UserspaceNetworking(true))
+	require.True(t, engine.New(engine.Podman).UserspaceNetworking(true))
+	require.False(t, engine.New(engine.Podman).UserspaceNetworking(false))
+}
+
+func TestEnv(t *testing.T) {
+	require.Nil(t, engine.New(engine.Docker).Env())
+	require.False(t, engine.New(engine.Docker).Remote())
+	require.Equal(t, []string{"DOCKER_HOST=ssh://me@server"}, engine.New(engine.Docker).WithHost("ssh://me@server").Env())
+	require.Equal(t, []string{"DOCKER_CONTEXT=server"}, engine.New(engine.Docker).WithHost("server").Env())
+	require.Equal(t, []string{"CONTAINER_HOST=ssh://me@server"}, engine.New(engine.Podman).WithHost("ssh://me@server").Env())
+	require.Equal(t, []string{"CONTAINER_CONNECTION=server"}, engine.New(engine.Podman).WithHost("server").Env())
+	require.True(t, engine.New(engine.Docker).WithHost("server").Remote())
+	require.False(t, engine.New(engine.Docker).WithHost("server").Local().Remote())
+}
@@ -6,25 +6,30 @@ import (
 	"os/exec"
 )
 
-func ValidateEnv() {
-	docker := "docker"
-	_, err := exec.LookPath(docker)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not find '%s': %v\n", docker, err)
-		os.Exit(1)
-	}
+// ValidateEnv makes sure that the container engine that runs the application (docker or podman) and tailscale are
+// installed
+func ValidateEnv(engineName string) {
+	lookPath(engineName)
+	lookPath("tailscale")
+}
 
-	tailscale := "tailscale"
-	_, err = exec.LookPath(tailscale)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not find '%s': %v\n", tailscale, err)
-		os.Exit(1)
+// ValidateSystemdEnv makes sure that the programs used by the systemd backend are installed. tailscaled is only
+// needed if the executable doesn't join the tailnet itself with tsnet.
+func ValidateSystemdEnv(tsnet bool) {
+	names := []string{"systemctl", "go", "tailscale"}
+	if !tsnet {
+		names = append(names, "tailscaled")
 	}
+	for _, name := range names {
+		lookPath(name)
+	}
+}
 
-	jq := "jq"
-	_, err = exec.LookPath(jq)
+// lookPath exits if name isn't installed
+func lookPath(name string) {
+	_, err := exec.LookPath(name)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Could not find '%s': %v\n", jq, err)
+		fmt.Fprintf(os.Stderr, "Could not find '%s': %v\n", name, err)
 		os.Exit(1)
 	}
 }
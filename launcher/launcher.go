@@ -0,0 +1,67 @@
+// Package launcher contains the source of gots-tsnet, a small launcher that joins the tailnet in-process with
+// tailscale.com/tsnet and runs the application as a child process. It's built as its own module so gots doesn't
+// depend on tailscale.com.
+package launcher
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The source has a go:build ignore constraint so it isn't built as part of gots
+//
+//go:embed tsnet/main.go
+var source string
+
+// The go.mod is named go.mod.txt as a go.mod would make tsnet its own module, which can't be embedded
+//
+//go:embed tsnet/go.mod.txt
+var goMod string
+
+//go:embed tsnet/go.sum
+var goSum string
+
+const (
+	// Name is the name of the launcher's executable and of the directory its module is written to
+	Name = "gots-tsnet"
+	// GoVersion is the Go version needed to build the launcher (the go directive of its go.mod)
+	GoVersion = "1.26"
+	// TailscaleVersion is the version of tailscale.com the launcher is built with
+	TailscaleVersion = "v1.94.2"
+)
+
+// GoMod returns the contents of the launcher's go.mod
+func GoMod() string {
+	return goMod
+}
+
+// GoSum returns the contents of the launcher's go.sum
+func GoSum() string {
+	return goSum
+}
+
+// Source returns the launcher's main.go
+func Source() string {
+	return strings.Replace(source, "//go:build ignore\n\n", "", 1)
+}
+
+// Write writes the launcher's module to dir/gots-tsnet and returns its path. Its dependencies are pinned by its go.sum
+// so it's built with -mod=readonly.
+func Write(dir string) (string, error) {
+	moduleDir := filepath.Join(dir, Name)
+	err := os.MkdirAll(moduleDir, 0755)
+	if err != nil {
+		return "", fmt.Errorf("Unable to create %s %w\n", moduleDir, err)
+	}
+	files := map[string]string{"go.mod": GoMod(), "go.sum": GoSum(), "main.go": Source()}
+	for name, content := range files {
+		err = os.WriteFile(filepath.Join(moduleDir, name), []byte(content), 0644)
+		if err != nil {
+			return "", fmt.Errorf("Unable to write %s %w\n", name, err)
+		}
+	}
+	return moduleDir, nil
+}
@@ -0,0 +1,54 @@
+package launcher_test
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/efarrer/gots/launcher"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	dir, err := launcher.Write(t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, launcher.Name, filepath.Base(dir))
+
+	goMod, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	require.NoError(t, err)
+	require.Contains(t, string(goMod), "module gots-tsnet\n")
+	require.Contains(t, string(goMod), "\ngo "+launcher.GoVersion+"\n")
+	require.Contains(t, string(goMod), "tailscale.com "+launcher.TailscaleVersion+"\n")
+	goSum, err := os.ReadFile(filepath.Join(dir, "go.sum"))
+	require.NoError(t, err)
+	require.Contains(t, string(goSum), "tailscale.com "+launcher.TailscaleVersion+" h1:")
+
+	// The build constraint that keeps it out of gots is removed
+	main, err := os.ReadFile(filepath.Join(dir, "main.go"))
+	require.NoError(t, err)
+	require.NotContains(t, string(main), "go:build")
+	file, err := parser.ParseFile(token.NewFileSet(), "main.go", main, parser.ImportsOnly)
+	require.NoError(t, err)
+	require.Equal(t, "main", file.Name.Name)
+}
+
+func TestSourceTypeChecks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the launcher's dependencies may need to be downloaded")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go isn't installed")
+	}
+	dir, err := launcher.Write(t.TempDir())
+	require.NoError(t, err)
+
+	// go vet type checks main.go and -mod=readonly fails if the go.mod or go.sum are incomplete
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=readonly")
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+}
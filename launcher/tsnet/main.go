@@ -0,0 +1,171 @@
+//go:build ignore
+
+// gots-tsnet joins the tailnet in-process with tsnet, applies the node's serve config, and runs the application as
+// a child process. It's used instead of a tailscale sidecar container so no privileged capabilities are needed.
+//
+//	gots-tsnet -hostname app [-serve-config serve.config] -- /bin/app args...
+//
+// It can also probe the application (gots-tsnet -probe http://127.0.0.1:8080/healthz) as a container healthcheck as
+// the application's image may not have any tools to do so.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"tailscale.com/ipn"
+	"tailscale.com/tsnet"
+)
+
+func main() {
+	hostname := flag.String("hostname", "", "The hostname of the tailnet node.")
+	stateDir := flag.String("state-dir", "/var/lib/tailscale", "The directory that holds the node's state.")
+	serveConfig := flag.String("serve-config", "", "The serve config to apply once the node is up.")
+	ephemeral := flag.Bool("ephemeral", false, "Register as an ephemeral node.")
+	tags := flag.String("tags", "", "The comma separated ACL tags to advertise.")
+	oauth := flag.Bool("oauth", false, "TS_AUTHKEY is an OAuth client secret.")
+	probeURL := flag.String("probe", "", "Probe the URL (http://... or tcp://...) and exit.")
+	flag.Parse()
+
+	if *probeURL != "" {
+		err := probe(*probeURL)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *hostname == "" || flag.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: gots-tsnet -hostname <hostname> [flags] -- <command> [args...]\n")
+		os.Exit(2)
+	}
+
+	srv := &tsnet.Server{
+		Hostname:  *hostname,
+		Dir:       *stateDir,
+		Ephemeral: *ephemeral,
+		UserLogf:  log.Printf,
+	}
+	if *tags != "" {
+		srv.AdvertiseTags = strings.Split(*tags, ",")
+	}
+	if *oauth {
+		srv.ClientSecret = os.Getenv("TS_AUTHKEY")
+	} else {
+		srv.AuthKey = os.Getenv("TS_AUTHKEY")
+	}
+	// The application doesn't need the auth key
+	os.Unsetenv("TS_AUTHKEY")
+	defer srv.Close()
+
+	// The application is started first so it's ready by the time the node is serving it
+	cmd := exec.Command(flag.Arg(0), flag.Args()[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Start()
+	if err != nil {
+		log.Fatalf("Unable to start %s: %v", flag.Arg(0), err)
+	}
+
+	// Signals are forwarded to the application and gots-tsnet exits when it does
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			cmd.Process.Signal(sig)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		err := up(ctx, srv, *serveConfig)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("Unable to serve on the tailnet: %v", err)
+			cmd.Process.Signal(syscall.SIGTERM)
+		}
+	}()
+
+	err = cmd.Wait()
+	cancel()
+	srv.Close()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		log.Fatalf("%s failed: %v", flag.Arg(0), err)
+	}
+}
+
+// up joins the tailnet and applies the serve config (if there is one)
+func up(ctx context.Context, srv *tsnet.Server, serveConfig string) error {
+	_, err := srv.Up(ctx)
+	if err != nil {
+		return err
+	}
+	if serveConfig == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(serveConfig)
+	if err != nil {
+		return err
+	}
+	// Like the tailscale container, ${TS_CERT_DOMAIN} is replaced with the node's domain
+	domains := srv.CertDomains()
+	if len(domains) == 0 {
+		return fmt.Errorf("HTTPS isn't enabled for the tailnet")
+	}
+	data = []byte(strings.ReplaceAll(string(data), "${TS_CERT_DOMAIN}", domains[0]))
+	cfg := ipn.ServeConfig{}
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return fmt.Errorf("Unable to parse %s: %w", serveConfig, err)
+	}
+
+	lc, err := srv.LocalClient()
+	if err != nil {
+		return err
+	}
+	return lc.SetServeConfig(ctx, &cfg)
+}
+
+// probe connects to a tcp:// URL or makes a GET request to an http:// URL. HTTP errors are failures.
+func probe(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "tcp" {
+		conn, err := net.DialTimeout("tcp", u.Host, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+	client := http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s returned %s", rawURL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package run
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake is a Runner for tests that records the commands instead of running them
+type Fake struct {
+	// Handler returns the result of each command. If it's nil every command succeeds without any output.
+	Handler func(cmd Cmd) (Result, error)
+
+	mu   sync.Mutex
+	cmds []Cmd
+}
+
+// Cmds returns the commands that were run in order. Both piped commands are included, from then to.
+func (f *Fake) Cmds() []Cmd {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Cmd{}, f.cmds...)
+}
+
+// Lines returns the command lines that were run in order
+func (f *Fake) Lines() []string {
+	lines := []string{}
+	for _, cmd := range f.Cmds() {
+		lines = append(lines, cmd.String())
+	}
+	return lines
+}
+
+// handle records the command and returns its result
+func (f *Fake) handle(ctx context.Context, cmd Cmd) (Result, error) {
+	f.mu.Lock()
+	f.cmds = append(f.cmds, cmd)
+	f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return Result{}, failed(ctx, err)
+	}
+	if f.Handler == nil {
+		return Result{}, nil
+	}
+	return f.Handler(cmd)
+}
+
+func (f *Fake) Run(ctx context.Context, cmd Cmd) (Result, error) {
+	return f.handle(ctx, cmd)
+}
+
+func (f *Fake) Pipe(ctx context.Context, from Cmd, to Cmd) (Result, error) {
+	fromResult, err := f.handle(ctx, from)
+	if err != nil {
+		return fromResult, err
+	}
+	toResult, err := f.handle(ctx, to)
+	toResult.Stderr = fromResult.Stderr + toResult.Stderr
+	return toResult, err
+}
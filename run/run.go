@@ -2,26 +2,265 @@ package run
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 )
 
-// RunWithOutput runs a commands and returns stdout, and stderr and any error if it failed.
-func RunWithOutput(name string, arg ...string) (string, string, error) {
-	cmd := exec.Command(name, arg...)
+// TailLines is the number of lines of a streamed command's output that are kept for error reports
+const TailLines = 20
 
-	var stdoutBuf, stderrBuf bytes.Buffer
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
-	err := cmd.Start()
-	if err != nil {
-		return stdoutBuf.String(), stderrBuf.String(), fmt.Errorf("start failed: %w", err)
+// stopDelay is how long a canceled command has to exit after it's interrupted before it's killed
+var stopDelay = 10 * time.Second
+
+// Cmd is a command to run
+type Cmd struct {
+	Name string
+	Args []string
+	// Dir is the directory the command runs in. Empty for the current directory.
+	Dir string
+	// Env are extra environment variables in "KEY=value" form
+	Env []string
+	// Stream copies the command's output to the terminal as it runs instead of capturing all of it. Only the last
+	// TailLines lines of its stdout and stderr are kept.
+	Stream bool
+	// Prefix is written before each streamed line
+	Prefix string
+}
+
+// String returns the command line
+func (c Cmd) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Result is a command's output. Only the tails of streamed output are kept.
+type Result struct {
+	Stdout string
+	Stderr string
+}
+
+// Runner runs commands. When the context is canceled (or times out) the command is interrupted and then killed.
+type Runner interface {
+	// Run runs the command and returns its output along with any error if it failed
+	Run(ctx context.Context, cmd Cmd) (Result, error)
+	// Pipe runs the from command piping its stdout to the to command. Result.Stdout is the to command's stdout and
+	// Result.Stderr is the stderr of both commands.
+	Pipe(ctx context.Context, from Cmd, to Cmd) (Result, error)
+}
+
+// Default is the Runner used to run gots' commands
+var Default Runner = Exec{}
+
+// SetDefault makes r the Default runner (e.g. a Fake in tests) and returns a function that restores the previous one
+func SetDefault(r Runner) func() {
+	previous := Default
+	Default = r
+	return func() { Default = previous }
+}
+
+// Run runs the command with the Default runner
+func Run(ctx context.Context, cmd Cmd) (Result, error) {
+	return Default.Run(ctx, cmd)
+}
+
+// Pipe pipes the from command to the to command with the Default runner
+func Pipe(ctx context.Context, from Cmd, to Cmd) (Result, error) {
+	return Default.Pipe(ctx, from, to)
+}
+
+// Exec is a Runner that runs commands on this machine
+type Exec struct {
+	// Stdout and Stderr are where streamed output is written. nil for os.Stdout and os.Stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// command creates the exec.Cmd for c
+func (e Exec) command(ctx context.Context, c Cmd) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, c.Name, c.Args...)
+	env := c.Env
+	if c.Dir != "" {
+		absDir, err := filepath.Abs(c.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve %s: %w", c.Dir, err)
+		}
+		cmd.Dir = absDir
+		// Keep PWD in sync with the directory as docker compose uses it to resolve ${PWD}
+		env = append([]string{"PWD=" + absDir}, env...)
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
 	}
+	// Interrupting lets commands like docker compose clean up
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = stopDelay
+	return cmd, nil
+}
 
+// outputs returns the writers that capture the command's stdout and stderr
+func (e Exec) outputs(c Cmd) (*output, *output) {
+	if !c.Stream {
+		return &output{}, &output{}
+	}
+	stdout, stderr := e.Stdout, e.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+	return &output{w: stdout, prefix: c.Prefix}, &output{w: stderr, prefix: c.Prefix}
+}
+
+// failed wraps the error from running a command. A canceled command reports why it was canceled.
+func failed(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("command stopped: %w", ctx.Err())
+	}
+	return fmt.Errorf("command failed: %w", err)
+}
+
+func (e Exec) Run(ctx context.Context, c Cmd) (Result, error) {
+	cmd, err := e.command(ctx, c)
+	if err != nil {
+		return Result{}, err
+	}
+	stdout, stderr := e.outputs(c)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	err = cmd.Start()
+	if err != nil {
+		return Result{}, fmt.Errorf("start failed: %w", err)
+	}
 	err = cmd.Wait()
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String()}
 	if err != nil {
-		return stdoutBuf.String(), stderrBuf.String(), fmt.Errorf("command failed: %w", err)
+		return result, failed(ctx, err)
 	}
+	return result, nil
+}
+
+func (e Exec) Pipe(ctx context.Context, from Cmd, to Cmd) (Result, error) {
+	fromCmd, err := e.command(ctx, from)
+	if err != nil {
+		return Result{}, err
+	}
+	toCmd, err := e.command(ctx, to)
+	if err != nil {
+		return Result{}, err
+	}
+	// Each command has its own stderr as they write to them concurrently
+	_, fromStderr := e.outputs(from)
+	stdout, toStderr := e.outputs(to)
+	if from.Stream && to.Stream {
+		w := &lockedWriter{w: fromStderr.w}
+		fromStderr.w, toStderr.w = w, w
+	}
+	fromCmd.Stderr = fromStderr
+	toCmd.Stdout = stdout
+	toCmd.Stderr = toStderr
+	r, w, err := os.Pipe()
+	if err != nil {
+		return Result{}, fmt.Errorf("pipe failed: %w", err)
+	}
+	fromCmd.Stdout = w
+	toCmd.Stdin = r
+
+	err = toCmd.Start()
+	if err != nil {
+		r.Close()
+		w.Close()
+		return Result{}, fmt.Errorf("start failed: %w", err)
+	}
+	err = fromCmd.Start()
+	// Only the commands hold the pipe now so from's writes fail once to exits and to reads EOF once from exits
+	r.Close()
+	w.Close()
+	if err != nil {
+		toCmd.Wait()
+		return Result{Stdout: stdout.String(), Stderr: toStderr.String()}, fmt.Errorf("start failed: %w", err)
+	}
+	toErr := toCmd.Wait()
+	fromErr := fromCmd.Wait()
+	result := Result{Stdout: stdout.String(), Stderr: fromStderr.String() + toStderr.String()}
+	// When to fails from's pipe breaks so to's error is the cause
+	if toErr != nil {
+		return result, failed(ctx, toErr)
+	}
+	if fromErr != nil {
+		return result, failed(ctx, fromErr)
+	}
+	return result, nil
+}
 
-	return stdoutBuf.String(), stderrBuf.String(), nil
+// lockedWriter serializes the writes of commands that stream to the same writer
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// output captures a command's stdout or stderr. If w isn't nil the output is copied to it a line at a time (with the
+// prefix) and only the last TailLines lines are kept.
+type output struct {
+	w      io.Writer
+	prefix string
+	// buf is all of the output if it isn't streamed, otherwise it's the last partial line
+	buf  bytes.Buffer
+	tail []string
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.buf.Write(p)
+	if o.w == nil {
+		return len(p), nil
+	}
+	for {
+		line, err := o.buf.ReadString('\n')
+		if err != nil {
+			// Keep the partial line until the rest of it is written
+			o.buf.Reset()
+			o.buf.WriteString(line)
+			return len(p), nil
+		}
+		o.line(strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// line streams a complete line and adds it to the tail
+func (o *output) line(line string) {
+	fmt.Fprintf(o.w, "%s%s\n", o.prefix, line)
+	o.tail = append(o.tail, line)
+	if len(o.tail) > TailLines {
+		o.tail = o.tail[len(o.tail)-TailLines:]
+	}
+}
+
+// String returns the captured output. It's only the tail for streamed output.
+func (o *output) String() string {
+	if o.w == nil {
+		return o.buf.String()
+	}
+	// The command has exited so any partial line is complete
+	if o.buf.Len() > 0 {
+		o.line(o.buf.String())
+		o.buf.Reset()
+	}
+	if len(o.tail) == 0 {
+		return ""
+	}
+	return strings.Join(o.tail, "\n") + "\n"
 }
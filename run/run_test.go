@@ -0,0 +1,141 @@
+package run_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efarrer/gots/run"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	result, err := run.Exec{}.Run(t.Context(), run.Cmd{
+		Name: "sh",
+		Args: []string{"-c", "pwd; echo $GOTS_TEST; echo err >&2"},
+		Dir:  dir,
+		Env:  []string{"GOTS_TEST=value"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, dir+"\nvalue\n", result.Stdout)
+	require.Equal(t, "err\n", result.Stderr)
+
+	result, err = run.Exec{}.Run(t.Context(), run.Cmd{Name: "sh", Args: []string{"-c", "echo failed >&2; exit 3"}})
+	require.ErrorContains(t, err, "command failed: exit status 3")
+	require.Equal(t, "failed\n", result.Stderr)
+
+	_, err = run.Exec{}.Run(t.Context(), run.Cmd{Name: "gots-no-such-command"})
+	require.ErrorContains(t, err, "start failed")
+}
+
+func TestRunStream(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	e := run.Exec{Stdout: stdout, Stderr: stderr}
+	// The last line doesn't end with a newline
+	script := fmt.Sprintf("for i in $(seq %d); do echo $i; done; echo warning >&2; printf last", run.TailLines+5)
+	result, err := e.Run(t.Context(), run.Cmd{Name: "sh", Args: []string{"-c", script}, Stream: true, Prefix: "build | "})
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	require.Len(t, lines, run.TailLines+6)
+	require.Equal(t, "build | 1", lines[0])
+	require.Equal(t, "build | last", lines[len(lines)-1])
+	require.Equal(t, "build | warning\n", stderr.String())
+
+	// Only the tail is kept
+	tail := strings.Split(strings.TrimSuffix(result.Stdout, "\n"), "\n")
+	require.Len(t, tail, run.TailLines)
+	require.Equal(t, "7", tail[0])
+	require.Equal(t, "last", tail[len(tail)-1])
+	require.Equal(t, "warning\n", result.Stderr)
+}
+
+func TestRunTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := run.Exec{}.Run(ctx, run.Cmd{Name: "sleep", Args: []string{"10"}})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "command stopped")
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestPipe(t *testing.T) {
+	result, err := run.Exec{}.Pipe(t.Context(),
+		run.Cmd{Name: "sh", Args: []string{"-c", "echo hello; echo from >&2"}},
+		run.Cmd{Name: "tr", Args: []string{"a-z", "A-Z"}},
+	)
+	require.NoError(t, err)
+	require.Equal(t, "HELLO\n", result.Stdout)
+	require.Equal(t, "from\n", result.Stderr)
+
+	_, err = run.Exec{}.Pipe(t.Context(),
+		run.Cmd{Name: "sh", Args: []string{"-c", "exit 2"}},
+		run.Cmd{Name: "cat"},
+	)
+	require.ErrorContains(t, err, "command failed: exit status 2")
+}
+
+func TestPipeStderr(t *testing.T) {
+	stderr := &bytes.Buffer{}
+	e := run.Exec{Stdout: &bytes.Buffer{}, Stderr: stderr}
+	script := "for i in $(seq 100); do echo %s $i >&2; done; echo data"
+	result, err := e.Pipe(t.Context(),
+		run.Cmd{Name: "sh", Args: []string{"-c", fmt.Sprintf(script, "from")}, Stream: true, Prefix: "ship | "},
+		run.Cmd{Name: "sh", Args: []string{"-c", "cat; " + fmt.Sprintf(script, "to")}, Stream: true, Prefix: "ship | "},
+	)
+	require.NoError(t, err)
+
+	// Both commands' output is streamed and their tails are kept
+	require.Len(t, strings.Split(strings.TrimSuffix(stderr.String(), "\n"), "\n"), 200)
+	require.Contains(t, stderr.String(), "ship | from 100\n")
+	require.Contains(t, stderr.String(), "ship | to 100\n")
+	tail := strings.Split(strings.TrimSuffix(result.Stderr, "\n"), "\n")
+	require.Len(t, tail, 2*run.TailLines)
+	require.Equal(t, "from 100", tail[run.TailLines-1])
+	require.Equal(t, "to 100", tail[len(tail)-1])
+}
+
+func TestPipeConsumerFails(t *testing.T) {
+	// from never finishes writing so it must be stopped when to exits
+	start := time.Now()
+	result, err := run.Exec{}.Pipe(t.Context(),
+		run.Cmd{Name: "yes"},
+		run.Cmd{Name: "sh", Args: []string{"-c", "echo disk full >&2; exit 1"}},
+	)
+	require.ErrorContains(t, err, "command failed: exit status 1")
+	require.Equal(t, "disk full\n", result.Stderr)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestFake(t *testing.T) {
+	someErr := errors.New("some error")
+	fake := &run.Fake{Handler: func(cmd run.Cmd) (run.Result, error) {
+		if cmd.Name == "false" {
+			return run.Result{Stderr: "failed\n"}, someErr
+		}
+		return run.Result{Stdout: cmd.String() + "\n"}, nil
+	}}
+	defer run.SetDefault(fake)()
+
+	result, err := run.Run(t.Context(), run.Cmd{Name: "echo", Args: []string{"hi"}})
+	require.NoError(t, err)
+	require.Equal(t, "echo hi\n", result.Stdout)
+
+	result, err = run.Pipe(t.Context(), run.Cmd{Name: "false"}, run.Cmd{Name: "cat"})
+	require.ErrorIs(t, err, someErr)
+	require.Equal(t, "failed\n", result.Stderr)
+
+	require.Equal(t, []string{"echo hi", "false"}, fake.Lines())
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = run.Run(ctx, run.Cmd{Name: "echo"})
+	require.ErrorIs(t, err, context.Canceled)
+}
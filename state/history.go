@@ -0,0 +1,94 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const historyFile = "history.json"
+
+// MaxImages is the number of deployed images that are kept in the history
+const MaxImages = 10
+
+// Image is a tagged image of the application that was deployed
+type Image struct {
+	Tag string
+	// Commit is the git commit the image was built from. Empty if the source isn't in git.
+	Commit string `json:"Commit,omitempty"`
+	Time   time.Time
+}
+
+// History records the application's deployed images so it can be rolled back
+type History struct {
+	// Current is the tag of the image that is running
+	Current string
+	// Images are the deployed images, oldest first
+	Images []Image
+}
+
+// Add records a newly deployed image as the current one. It returns the images that no longer fit in the history.
+func (h *History) Add(image Image) []Image {
+	h.Current = image.Tag
+	h.Images = append(h.Images, image)
+	if len(h.Images) <= MaxImages {
+		return nil
+	}
+	dropped := slices.Clone(h.Images[:len(h.Images)-MaxImages])
+	h.Images = slices.Clone(h.Images[len(h.Images)-MaxImages:])
+	return dropped
+}
+
+// Find returns the image with the tag. It returns nil if it isn't in the history.
+func (h History) Find(tag string) *Image {
+	i := slices.IndexFunc(h.Images, func(image Image) bool { return image.Tag == tag })
+	if i == -1 {
+		return nil
+	}
+	return &h.Images[i]
+}
+
+// Previous returns the image that was deployed before the current one. It returns nil if there isn't one.
+func (h History) Previous() *Image {
+	i := slices.IndexFunc(h.Images, func(image Image) bool { return image.Tag == h.Current })
+	if i < 1 {
+		return nil
+	}
+	return &h.Images[i-1]
+}
+
+// LoadHistory loads the image history from the state directory. It's empty if nothing has been deployed.
+func LoadHistory(dir string) (History, error) {
+	h := History{}
+	data, err := os.ReadFile(filepath.Join(dir, historyFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, fmt.Errorf("Unable to read %s %w\n", historyFile, err)
+	}
+
+	err = json.Unmarshal(data, &h)
+	if err != nil {
+		return h, fmt.Errorf("Unable to parse %s %w\n", historyFile, err)
+	}
+	return h, nil
+}
+
+// SaveHistory saves the image history to the state directory
+func SaveHistory(dir string, h History) error {
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to JSONify history\n")
+	}
+	err = os.WriteFile(filepath.Join(dir, historyFile), data, 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to save %s %w\n", historyFile, err)
+	}
+	return nil
+}
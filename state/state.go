@@ -0,0 +1,78 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const deploymentFile = "deployment.json"
+
+// Deployment records the last time the application was started or stopped
+type Deployment struct {
+	Action  string
+	Service string `json:"Service,omitempty"`
+	Time    time.Time
+}
+
+// Dir returns the state directory for the application ($XDG_STATE_HOME/gots/<hostname> or
+// ~/.local/state/gots/<hostname>). It holds the generated files used to run the application so they outlive gots.
+func Dir(hostname string) (string, error) {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("Unable to find the home directory %w\n", err)
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, "gots", hostname), nil
+}
+
+// Create returns the state directory for the application creating it if needed
+func Create(hostname string) (string, error) {
+	dir, err := Dir(hostname)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("Unable to create %s %w\n", dir, err)
+	}
+	return dir, nil
+}
+
+// LoadDeployment loads the last deployment from the state directory. It returns nil if there hasn't been one.
+func LoadDeployment(dir string) (*Deployment, error) {
+	data, err := os.ReadFile(filepath.Join(dir, deploymentFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s %w\n", deploymentFile, err)
+	}
+
+	d := Deployment{}
+	err = json.Unmarshal(data, &d)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse %s %w\n", deploymentFile, err)
+	}
+	return &d, nil
+}
+
+// SaveDeployment saves the deployment to the state directory
+func SaveDeployment(dir string, d Deployment) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("Unable to JSONify deployment\n")
+	}
+	err = os.WriteFile(filepath.Join(dir, deploymentFile), data, 0644)
+	if err != nil {
+		return fmt.Errorf("Unable to save %s %w\n", deploymentFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package state_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/efarrer/gots/state"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/xdg/state")
+	dir, err := state.Dir("app")
+	require.NoError(t, err)
+	require.Equal(t, "/xdg/state/gots/app", dir)
+
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/me")
+	dir, err = state.Dir("app")
+	require.NoError(t, err)
+	require.Equal(t, "/home/me/.local/state/gots/app", dir)
+}
+
+func TestCreate(t *testing.T) {
+	base := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", base)
+
+	dir, err := state.Create("app")
+	require.NoError(t, err)
+	require.DirExists(t, filepath.Join(base, "gots", "app"))
+	require.Equal(t, filepath.Join(base, "gots", "app"), dir)
+}
+
+func TestDeployment(t *testing.T) {
+	dir := t.TempDir()
+
+	d, err := state.LoadDeployment(dir)
+	require.NoError(t, err)
+	require.Nil(t, d)
+
+	expected := state.Deployment{Action: "start", Service: "db", Time: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
+	require.NoError(t, state.SaveDeployment(dir, expected))
+	d, err = state.LoadDeployment(dir)
+	require.NoError(t, err)
+	require.Equal(t, expected, *d)
+}
+
+func TestHistory(t *testing.T) {
+	dir := t.TempDir()
+
+	h, err := state.LoadHistory(dir)
+	require.NoError(t, err)
+	require.Nil(t, h.Previous())
+
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := range state.MaxImages {
+		require.Nil(t, h.Add(state.Image{Tag: fmt.Sprint(i), Time: start.Add(time.Duration(i) * time.Minute)}))
+	}
+	require.Equal(t, "9", h.Current)
+	require.Equal(t, "8", h.Previous().Tag)
+
+	// The oldest images are dropped
+	dropped := h.Add(state.Image{Tag: "10", Commit: "abc123", Time: start.Add(time.Hour)})
+	require.Equal(t, []state.Image{{Tag: "0", Time: start}}, dropped)
+	require.Len(t, h.Images, state.MaxImages)
+	require.Nil(t, h.Find("0"))
+	require.Equal(t, "abc123", h.Find("10").Commit)
+
+	// Rolling back makes an earlier image current
+	h.Current = "1"
+	require.Nil(t, h.Previous())
+
+	require.NoError(t, state.SaveHistory(dir, h))
+	loaded, err := state.LoadHistory(dir)
+	require.NoError(t, err)
+	require.Equal(t, h, loaded)
+}
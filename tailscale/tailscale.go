@@ -0,0 +1,52 @@
+package tailscale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/efarrer/gots/run"
+)
+
+// Peer is the subset of a tailnet peer reported by `tailscale status --json` that gots uses
+type Peer struct {
+	HostName     string
+	DNSName      string
+	TailscaleIPs []string
+	Online       bool
+}
+
+// Status is the subset of `tailscale status --json` that gots uses
+type Status struct {
+	Peer map[string]Peer
+}
+
+// ParseStatus parses the output of `tailscale status --json`
+func ParseStatus(data []byte) (*Status, error) {
+	status := Status{}
+	err := json.Unmarshal(data, &status)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse tailscale status %w", err)
+	}
+	return &status, nil
+}
+
+// GetStatus runs `tailscale status --json` and parses the result
+func GetStatus(ctx context.Context) (*Status, error) {
+	result, err := run.Run(ctx, run.Cmd{Name: "tailscale", Args: []string{"status", "--json"}})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get tailscale status %w\n%s", err, result.Stderr)
+	}
+	return ParseStatus([]byte(result.Stdout))
+}
+
+// FindPeer returns the peer whose hostname contains hostname or nil if there is no such peer
+func (s *Status) FindPeer(hostname string) *Peer {
+	for _, peer := range s.Peer {
+		if strings.Contains(peer.HostName, hostname) {
+			return &peer
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package tailscale_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/tailscale"
+	"github.com/stretchr/testify/require"
+)
+
+const statusJSON = `{
+  "Version": "1.80.0",
+  "Self": {"HostName": "laptop"},
+  "Peer": {
+    "nodekey:abc": {
+      "HostName": "myapp",
+      "DNSName": "myapp.tail1234.ts.net.",
+      "TailscaleIPs": ["100.64.0.1", "fd7a:115c:a1e0::1"],
+      "Online": true
+    },
+    "nodekey:def": {
+      "HostName": "other",
+      "DNSName": "other.tail1234.ts.net.",
+      "TailscaleIPs": ["100.64.0.2"],
+      "Online": false
+    }
+  }
+}`
+
+func TestParseStatus(t *testing.T) {
+	status, err := tailscale.ParseStatus([]byte(statusJSON))
+	require.NoError(t, err)
+	require.Len(t, status.Peer, 2)
+
+	_, err = tailscale.ParseStatus([]byte("not json"))
+	require.Error(t, err)
+}
+
+func TestFindPeer(t *testing.T) {
+	status, err := tailscale.ParseStatus([]byte(statusJSON))
+	require.NoError(t, err)
+
+	peer := status.FindPeer("myapp")
+	require.NotNil(t, peer)
+	require.Equal(t, "myapp.tail1234.ts.net.", peer.DNSName)
+	require.Equal(t, []string{"100.64.0.1", "fd7a:115c:a1e0::1"}, peer.TailscaleIPs)
+	require.True(t, peer.Online)
+
+	require.Nil(t, status.FindPeer("missing"))
+}
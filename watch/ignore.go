@@ -0,0 +1,124 @@
+package watch
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// pattern is a single .gitignore pattern
+type pattern struct {
+	// base is the directory (relative to the watched directory) of the .gitignore that the pattern came from
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Ignore matches paths against .gitignore patterns
+type Ignore struct {
+	patterns []pattern
+}
+
+// Add adds the patterns from a .gitignore in the base directory. base is relative to the watched directory and uses
+// forward slashes ("" for the watched directory itself).
+func (ig *Ignore) Add(base string, data []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := pattern{base: base}
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			// Escapes a leading # or !
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		// A pattern with a slash is relative to its .gitignore, otherwise it matches a name at any depth
+		prefix := "^(.*/)?"
+		if strings.Contains(line, "/") {
+			prefix = "^"
+			line = strings.TrimPrefix(line, "/")
+		}
+		re, err := regexp.Compile(prefix + globToRegexp(line) + "$")
+		if err != nil {
+			continue
+		}
+		p.re = re
+		ig.patterns = append(ig.patterns, p)
+	}
+}
+
+// Ignored returns true if the path (relative to the watched directory with forward slashes) is ignored. The last
+// matching pattern wins so negated patterns can re-include paths.
+func (ig *Ignore) Ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range ig.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		name := rel
+		if p.base != "" {
+			if !strings.HasPrefix(rel, p.base+"/") {
+				continue
+			}
+			name = strings.TrimPrefix(rel, p.base+"/")
+		}
+		if p.re.MatchString(name) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp converts a .gitignore glob into a regular expression
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			re.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			re.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
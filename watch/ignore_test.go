@@ -0,0 +1,44 @@
+package watch_test
+
+import (
+	"testing"
+
+	"github.com/efarrer/gots/watch"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnore(t *testing.T) {
+	ig := &watch.Ignore{}
+	ig.Add("", []byte(`# Build output
+/bin
+*.log
+!keep.log
+tmp/
+docs/**/*.png
+\#notes
+`))
+	ig.Add("web", []byte("node_modules/\n/dist\n"))
+
+	for rel, ignored := range map[string]bool{
+		"bin":                  true,
+		"cmd/bin":              false,
+		"app.log":              true,
+		"logs/app.log":         true,
+		"keep.log":             false,
+		"docs/a/b/diagram.png": true,
+		"docs/diagram.png":     true,
+		"#notes":               true,
+		"main.go":              false,
+		"web/dist":             true,
+		"dist":                 false,
+		"web/src/node_modules": true,
+		"other/node_modules":   false,
+		"web/src/index.ts":     false,
+	} {
+		require.Equal(t, ignored, ig.Ignored(rel, rel != "main.go" && rel != "app.log"), rel)
+	}
+
+	// Directory patterns don't match files
+	require.False(t, ig.Ignored("tmp", false))
+	require.True(t, ig.Ignored("tmp", true))
+}
@@ -0,0 +1,149 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// alwaysIgnored are never watched. .tailscale holds the state of the application's tailnet node which changes while
+// it runs.
+var alwaysIgnored = []string{".git", ".tailscale"}
+
+// file is the state of a watched file used to detect changes
+type file struct {
+	modTime time.Time
+	size    int64
+	mode    fs.FileMode
+}
+
+// Snapshot is the state of the files in a directory
+type Snapshot map[string]file
+
+// Take returns a snapshot of the files in dir that aren't ignored by a .gitignore (in dir or any of its
+// subdirectories), alwaysIgnored, or the extra names.
+func Take(dir string, extra ...string) (Snapshot, error) {
+	snapshot := Snapshot{}
+	ignore := &Ignore{}
+	ignore.Add("", []byte(strings.Join(append(slices.Clone(alwaysIgnored), extra...), "\n")))
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files can be removed while walking
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rel = ""
+		} else if ignore.Ignored(rel, d.IsDir()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			// The patterns only apply to the directory's contents
+			if data, err := os.ReadFile(filepath.Join(p, ".gitignore")); err == nil {
+				ignore.Add(rel, data)
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		snapshot[rel] = file{modTime: info.ModTime(), size: info.Size(), mode: info.Mode()}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s %w\n", dir, err)
+	}
+	return snapshot, nil
+}
+
+// Changes returns the files that were added, modified, or removed since the earlier snapshot, sorted by name
+func (s Snapshot) Changes(earlier Snapshot) []string {
+	changes := []string{}
+	for name, f := range s {
+		if old, ok := earlier[name]; !ok || old != f {
+			changes = append(changes, name)
+		}
+	}
+	for name := range earlier {
+		if _, ok := s[name]; !ok {
+			changes = append(changes, name)
+		}
+	}
+	slices.Sort(changes)
+	return changes
+}
+
+// Watcher polls a directory for changes
+type Watcher struct {
+	Dir string
+	// Ignore are extra names that are ignored like they were listed in a .gitignore in Dir
+	Ignore []string
+	// Interval is the time between polls
+	Interval time.Duration
+	// Debounce is how long the files must stay unchanged before the changes are reported, so saving several files
+	// (or a checkout) is reported once
+	Debounce time.Duration
+}
+
+// Run calls changed with the files that changed after each burst of changes until done is closed. changed is called
+// from Run's goroutine so changes made while it runs are reported after it returns.
+func (w Watcher) Run(done <-chan struct{}, changed func(files []string)) error {
+	snapshot, err := Take(w.Dir, w.Ignore...)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	pending := map[string]bool{}
+	lastChange := time.Time{}
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+
+		current, err := Take(w.Dir, w.Ignore...)
+		if err != nil {
+			return err
+		}
+		changes := current.Changes(snapshot)
+		snapshot = current
+		for _, name := range changes {
+			pending[name] = true
+		}
+		if len(changes) > 0 {
+			lastChange = time.Now()
+			continue
+		}
+		if len(pending) > 0 && time.Since(lastChange) >= w.Debounce {
+			files := []string{}
+			for name := range pending {
+				files = append(files, name)
+			}
+			slices.Sort(files)
+			pending = map[string]bool{}
+			changed(files)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package watch_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/efarrer/gots/watch"
+	"github.com/stretchr/testify/require"
+)
+
+// write creates the file (and its directory) in dir
+func write(t *testing.T, dir string, name string, content string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func TestTake(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "main.go", "package main")
+	write(t, dir, ".gitignore", "*.out\n")
+	write(t, dir, "app.out", "binary")
+	write(t, dir, "web/.gitignore", "dist/\n")
+	write(t, dir, "web/dist/app.js", "")
+	write(t, dir, "web/index.ts", "")
+	write(t, dir, ".tailscale/tailscaled.state", "{}")
+	write(t, dir, ".git/HEAD", "ref: refs/heads/main")
+	write(t, dir, ".gots", "{}")
+
+	before, err := watch.Take(dir, "/.gots")
+	require.NoError(t, err)
+	require.Empty(t, before.Changes(before))
+
+	names := watch.Snapshot{}.Changes(before)
+	require.Equal(t, []string{".gitignore", "main.go", "web/.gitignore", "web/index.ts"}, names)
+
+	write(t, dir, "main.go", "package main\n\nfunc main() {}")
+	write(t, dir, "util.go", "package main")
+	require.NoError(t, os.Remove(filepath.Join(dir, "web/index.ts")))
+	write(t, dir, "web/dist/app.js", "changed")
+	write(t, dir, ".gots", `{"Port": 80}`)
+	after, err := watch.Take(dir, "/.gots")
+	require.NoError(t, err)
+	require.Equal(t, []string{"main.go", "util.go", "web/index.ts"}, after.Changes(before))
+}
+
+func TestWatcher(t *testing.T) {
+	dir := t.TempDir()
+	write(t, dir, "main.go", "package main")
+
+	changes := make(chan []string)
+	done := make(chan struct{})
+	errs := make(chan error)
+	w := watch.Watcher{Dir: dir, Interval: 10 * time.Millisecond, Debounce: 200 * time.Millisecond}
+	go func() {
+		errs <- w.Run(done, func(files []string) { changes <- files })
+	}()
+
+	// Let the watcher take its first snapshot then make a burst of changes that are reported together
+	time.Sleep(100 * time.Millisecond)
+	write(t, dir, "a.go", "package main")
+	time.Sleep(20 * time.Millisecond)
+	write(t, dir, "b.go", "package main")
+	select {
+	case files := <-changes:
+		require.Equal(t, []string{"a.go", "b.go"}, files)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "the changes weren't reported")
+	}
+
+	close(done)
+	require.NoError(t, <-errs)
+}